@@ -0,0 +1,65 @@
+// Package that abstracts time away from the code waiting for delays, so tests can control it.
+//
+// System is the clock backed by the time package and is used whenever no clock is configured,
+// Manual is a fake clock which only moves when it's advanced.
+package clock
+
+import "time"
+
+// Source of the current time and timers
+type Clock interface {
+	Now() time.Time
+	// Returns a channel which receives the current time once the duration elapses
+	After(duration time.Duration) <-chan time.Time
+	NewTimer(duration time.Duration) Timer
+}
+
+// Counterpart of time.Timer which can be faked
+type Timer interface {
+	C() <-chan time.Time
+	// Returns false if the timer already fired or was stopped, same as time.Timer.Stop
+	Stop() bool
+	// Returns false if the timer already fired or was stopped, same as time.Timer.Reset
+	Reset(duration time.Duration) bool
+}
+
+// Clock backed by the time package
+var System Clock = systemClock{}
+
+// Returns clock or System when it's nil
+func OrSystem(clock Clock) Clock {
+	if clock == nil {
+		return System
+	}
+	return clock
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(duration time.Duration) <-chan time.Time {
+	return time.After(duration)
+}
+
+func (systemClock) NewTimer(duration time.Duration) Timer {
+	return &systemTimer{timer: time.NewTimer(duration)}
+}
+
+type systemTimer struct {
+	timer *time.Timer
+}
+
+func (t *systemTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t *systemTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (t *systemTimer) Reset(duration time.Duration) bool {
+	return t.timer.Reset(duration)
+}
@@ -0,0 +1,133 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake clock for tests, its time only moves with Advance and Set which fire the timers that became due.
+//
+// Code under test usually waits in another goroutine, WaitForTimers lets the test wait until it starts waiting
+// before the clock is advanced.
+type Manual struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*manualTimer
+}
+
+// Creates Manual clock starting at now
+func NewManual(now time.Time) *Manual {
+	manual := &Manual{now: now}
+	manual.cond = sync.NewCond(&manual.mutex)
+	return manual
+}
+
+func (m *Manual) Now() time.Time {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.now
+}
+
+func (m *Manual) After(duration time.Duration) <-chan time.Time {
+	return m.NewTimer(duration).C()
+}
+
+func (m *Manual) NewTimer(duration time.Duration) Timer {
+	timer := &manualTimer{clock: m, c: make(chan time.Time, 1)}
+	timer.Reset(duration)
+	return timer
+}
+
+// Moves the clock forward by duration and fires timers which became due in order of their deadlines
+func (m *Manual) Advance(duration time.Duration) {
+	m.Set(m.Now().Add(duration))
+}
+
+// Moves the clock to now and fires timers which became due in order of their deadlines
+func (m *Manual) Set(now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.now = now
+
+	sort.SliceStable(m.timers, func(i, j int) bool {
+		return m.timers[i].deadline.Before(m.timers[j].deadline)
+	})
+	var pending []*manualTimer
+	for _, timer := range m.timers {
+		if timer.deadline.After(now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.fire(now)
+	}
+	m.timers = pending
+	m.cond.Broadcast()
+}
+
+// Number of timers which are waiting to fire
+func (m *Manual) Timers() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.timers)
+}
+
+// Blocks until at least count timers are waiting to fire
+func (m *Manual) WaitForTimers(count int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for len(m.timers) < count {
+		m.cond.Wait()
+	}
+}
+
+// Removes timer from the pending ones, returns false when it wasn't pending
+func (m *Manual) remove(timer *manualTimer) bool {
+	for i, pending := range m.timers {
+		if pending == timer {
+			m.timers = append(m.timers[:i], m.timers[i+1:]...)
+			m.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type manualTimer struct {
+	clock    *Manual
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	return t.clock.remove(t)
+}
+
+func (t *manualTimer) Reset(duration time.Duration) bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	active := t.clock.remove(t)
+	t.deadline = t.clock.now.Add(duration)
+	if duration <= 0 {
+		t.fire(t.clock.now)
+		return active
+	}
+	t.clock.timers = append(t.clock.timers, t)
+	t.clock.cond.Broadcast()
+	return active
+}
+
+// Same as time.Timer, the time is dropped when the previous one wasn't received yet
+func (t *manualTimer) fire(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
@@ -0,0 +1,71 @@
+package clock
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestManualFiresTimersOnceDue(t *testing.T) {
+	t.Logf("Given Manual clock with timers of 1s and 3s")
+	clock := NewManual(start)
+	first := clock.NewTimer(time.Second)
+	second := clock.After(3 * time.Second)
+
+	t.Logf("When advancing the clock by 2s")
+	clock.Advance(2 * time.Second)
+
+	t.Logf("Should fire only the first timer with the current time")
+	assert.Equal(t, start.Add(2*time.Second), <-first.C())
+	assert.Empty(t, second)
+	assert.Equal(t, 1, clock.Timers())
+
+	t.Logf("When advancing the clock by another 1s")
+	clock.Advance(time.Second)
+
+	t.Logf("Should fire the second timer")
+	assert.Equal(t, start.Add(3*time.Second), <-second)
+	assert.Equal(t, 0, clock.Timers())
+}
+
+func TestManualTimerStopAndReset(t *testing.T) {
+	t.Logf("Given Manual clock with a timer of 1s")
+	clock := NewManual(start)
+	timer := clock.NewTimer(time.Second)
+
+	t.Logf("When stopping the timer and advancing the clock")
+	stopped := timer.Stop()
+	clock.Advance(time.Second)
+
+	t.Logf("Should not fire the timer")
+	assert.True(t, stopped)
+	assert.False(t, timer.Stop())
+	assert.Empty(t, timer.C())
+
+	t.Logf("When resetting the timer to 5s")
+	assert.False(t, timer.Reset(5*time.Second))
+	clock.Advance(4 * time.Second)
+
+	t.Logf("Should fire 5s after the reset")
+	assert.Empty(t, timer.C())
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(6*time.Second), <-timer.C())
+}
+
+func TestManualWaitForTimers(t *testing.T) {
+	t.Logf("Given Manual clock and a goroutine sleeping for 1m")
+	clock := NewManual(start)
+	done := make(chan time.Time)
+	go func() {
+		done <- <-clock.After(time.Minute)
+	}()
+
+	t.Logf("When waiting for the goroutine to sleep and advancing the clock")
+	clock.WaitForTimers(1)
+	clock.Advance(time.Minute)
+
+	t.Logf("Should wake the goroutine up without waiting for a minute")
+	assert.Equal(t, start.Add(time.Minute), <-done)
+}
@@ -5,7 +5,7 @@
 // The NewClient function creates a new instance of the Client by providing ClientConfig.
 // It is required to pass all the fields from that config
 //
-// For the time being it only provides GET, POST and DELETE operations.
+// It provides GET, HEAD, POST, PUT and DELETE operations, Do for any other method and Stream for server-sent events.
 //
 //
 //
@@ -14,26 +14,73 @@ package http
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	corehttp "net/http"
+	neturl "net/url"
+	"reflect"
+	"sync"
+	"test2/clock"
 	"test2/http/retry"
 	"time"
 )
 
 type ClientConfig struct {
+	// Timeout of a single attempt, including reading of the response body
 	Timeout time.Duration
-	Retries retry.RetriesConfig
-	Headers Headers
-	Logging bool
+	// Optional timeout of the whole operation including all the retries and delays between them
+	TotalTimeout time.Duration
+	Retries      retry.RetriesConfig
+	// Optional hedging of GET requests, disabled by default
+	Hedging HedgingConfig
+	// Optional deduplication of concurrent, identical GET requests, disabled by default
+	Coalescing CoalescingConfig
+	// Optional load balancing of relative urls between several endpoints
+	Endpoints EndpointsConfig
+	// Optional compression of request and response bodies
+	Compression CompressionConfig
+	// Media type used to encode request bodies, defaults to application/json
+	ContentType string
+	// Additional codecs, shipped JSON, XML, MessagePack and form codecs are always available
+	Codecs []Codec
+	// How responses without a meaningful Content-Type (missing, text/plain, application/octet-stream) are handled,
+	// they're logged and decoded by default
+	ContentTypeCheck ContentTypeCheck
+	Headers          Headers
+	Logging          bool
+	Transport        TransportConfig
+
+	// Optional, caller-supplied http.Client, if set Transport and RoundTripper are ignored.
+	// Its Timeout is only overridden when it's zero
+	HttpClient *corehttp.Client
+	// Optional, caller-supplied RoundTripper, if set Transport is ignored
+	RoundTripper corehttp.RoundTripper
+	// Optional clock used for durations, hedging delays, ejections and periodic health checks and discovery,
+	// also used by the retries unless Retries.Clock is set and by Stream waiting for the headers.
+	// Defaults to clock.System, timeouts of requests always use real time
+	Clock clock.Clock
 }
 
 type Client struct {
-	client  *corehttp.Client
-	retry   *retry.Retry
-	headers Headers
-	logging bool
+	// Config the Client was built from, with defaults applied
+	config      ClientConfig
+	client      *corehttp.Client
+	retry       *retry.Retry
+	headers     Headers
+	logging     bool
+	timeouts    map[TimeoutBudget]time.Duration
+	hedger      *hedger
+	coalescer   *coalescer
+	endpoints   *endpoints
+	compression *compression
+	codecs      *codecs
+	typeCheck   ContentTypeCheck
+	metrics     *metrics
+	clock       clock.Clock
+	closeOnce   sync.Once
 }
 
 type Headers map[string]string
@@ -42,102 +89,334 @@ type Headers map[string]string
 //
 // If ClientConfig.Timeout is zero or bellow it returns TimeoutZeroError.
 //
+// If ClientConfig.TotalTimeout is below zero it returns TotalTimeoutNegativeError, zero means no total timeout.
+//
 // If ClientConfig.RetriesConfig has any errors, those will be also returned to the caller,
 // not providing those values is not possible as retries are required on all of the endpoints
 //
+// If ClientConfig.Hedging is enabled without delay nor percentile it returns HedgingDelayZeroError,
+// if its percentile is out of (0-100] range it returns HedgingPercentileError.
+//
+// If any of ClientConfig.Endpoints is not an absolute url or has a negative weight it returns InvalidEndpointError,
+// if the initial resolution of ClientConfig.Endpoints.SRV records fails it returns DiscoveryError.
+// The Client has to be closed with Close once the endpoints are health-checked or discovered.
+//
+// If ClientConfig.Compression contains an encoding other than gzip, deflate or zstd it returns UnsupportedEncodingError.
+//
+// If none of the codecs handles ClientConfig.ContentType it returns UnknownContentTypeError.
+//
 // If Headers won't be empty, all the headers will be set on every outgoing http request
 //
+// If HttpClient or RoundTripper are provided they are used as they are, otherwise a new transport is built
+// from Transport, any error while building it (e.g. InvalidCACertError) is returned to the caller
+//
 // If Logging is enabled, every outgoing request will be logged along with its execution time, including retries
 func NewClient(config ClientConfig) (*Client, error) {
+	return newClient(config, nil)
+}
+
+// Creates a new Client from config which carries over the long-lived parts of c: its metrics, latencies observed
+// by hedging and, as long as their settings didn't change, the connection pool, the coalescer and the endpoints
+// along with their health and discovery. Errors are the same as the ones returned by NewClient.
+//
+// c keeps working for requests in flight and has to be closed, which stops only the parts that weren't carried over.
+func (c *Client) Reconfigure(config ClientConfig) (*Client, error) {
+	return newClient(config, c)
+}
+
+// Builds the Client, previous is the Client it replaces or nil
+func newClient(config ClientConfig, previous *Client) (*Client, error) {
 	if config.Timeout.Milliseconds() <= 0 {
 		return nil, TimeoutZeroError
 	}
+	if config.TotalTimeout < 0 {
+		return nil, TotalTimeoutNegativeError
+	}
 
+	config.Clock = clock.OrSystem(config.Clock)
+	if config.Retries.Clock == nil {
+		config.Retries.Clock = config.Clock
+	}
 	retry, err := retry.NewRetries(config.Retries)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := validateHedging(config.Hedging); err != nil {
+		return nil, err
+	}
+	if err := validateEndpoints(config.Endpoints); err != nil {
+		return nil, err
+	}
+	if err := validateCompression(config.Compression); err != nil {
+		return nil, err
+	}
+	codecs, err := newCodecs(config.ContentType, config.Codecs)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newHttpClient(config, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &metrics{}
+	latencies := &latencies{}
+	var coalescer *coalescer
+	var endpoints *endpoints
+	if previous != nil {
+		metrics = previous.metrics
+		if previous.hedger != nil {
+			latencies = previous.hedger.latencies
+		}
+		if previous.coalescer != nil && reflect.DeepEqual(previous.config.Coalescing, config.Coalescing) {
+			coalescer = previous.coalescer
+		}
+		if previous.endpoints != nil && previous.client.Transport == client.Transport && previous.clock == config.Clock &&
+			reflect.DeepEqual(previous.config.Endpoints, config.Endpoints) {
+			endpoints = previous.endpoints.retain()
+		}
+	}
+	if coalescer == nil {
+		coalescer = newCoalescer(config.Coalescing, metrics)
+	}
+	if endpoints == nil {
+		endpoints, err = newEndpoints(config.Endpoints, client, metrics, config.Clock, config.Logging)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var hedger *hedger
+	if config.Hedging.enabled() {
+		hedger = newHedger(config.Hedging, metrics, latencies, config.Clock, config.Logging)
+	}
 	return &Client{
-		client:  &corehttp.Client{Timeout: config.Timeout},
-		retry:   retry,
-		headers: config.Headers,
-		logging: config.Logging,
+		config:      config,
+		client:      client,
+		retry:       retry,
+		headers:     config.Headers,
+		logging:     config.Logging,
+		timeouts:    newTimeouts(config, client),
+		hedger:      hedger,
+		coalescer:   coalescer,
+		endpoints:   endpoints,
+		compression: newCompression(config.Compression),
+		codecs:      codecs,
+		typeCheck:   config.ContentTypeCheck,
+		metrics:     metrics,
+		clock:       config.Clock,
 	}, nil
 }
 
-// Runs GET HTTP query for provided url, responseBody (pointer) will be written by json.Unmarshal.
+// The transport built by the previous Client is carried over along with its connection pool
+// unless Transport settings changed
+func newHttpClient(config ClientConfig, previous *Client) (*corehttp.Client, error) {
+	if config.HttpClient != nil {
+		client := *config.HttpClient
+		if client.Timeout <= 0 {
+			client.Timeout = config.Timeout
+		}
+		return &client, nil
+	}
+
+	roundTripper := config.RoundTripper
+	if roundTripper == nil && previous != nil && previous.config.HttpClient == nil && previous.config.RoundTripper == nil &&
+		reflect.DeepEqual(previous.config.Transport, config.Transport) {
+		roundTripper = previous.client.Transport
+	}
+	if roundTripper == nil {
+		transport, err := NewTransport(config.Transport)
+		if err != nil {
+			return nil, err
+		}
+		roundTripper = transport
+	}
+	return &corehttp.Client{Timeout: config.Timeout, Transport: roundTripper}, nil
+}
+
+// Runs GET HTTP query for provided url, responseBody (pointer) will be written by the codec matching
+// response's Content-Type (JSON by default).
 //
 // In case of network, parsing or io error (non http related) it will return ClientError.
 //
 // In case of an http related error (>400 status code) it will return ClientHttpError along with returned status code.
+//
+// In case there's no codec for response's Content-Type it will return UnsupportedMediaTypeError.
+//
+// In case any of the timeouts expires it will return TimeoutError with the budget that expired.
+//
+// 204 and 205 responses are not decoded and leave responseBody untouched, other 2xx responses with an empty body
+// return ClientError wrapping EmptyBodyError. responseBody may be nil when the body is not needed.
 func (c *Client) Get(ctx context.Context, url string, responseBody interface{}) error {
-	method := "GET"
-	request, err := c.createRequest(ctx, method, url, nil)
-	if err != nil {
-		return err
-	}
-
-	response, err := c.executeWithRetry(request)
-	if err != nil {
-		return err
-	}
-
-	return readResponse(response, err, url, responseBody)
+	return c.execute(ctx, "GET", url, nil, responseBody)
 }
 
-// Runs GET HTTP query for provided url, responseBody (pointer) will be written by json.Unmarshal.
+// Runs DELETE HTTP query for provided url, responseBody (pointer) will be written by the codec matching
+// response's Content-Type (JSON by default).
 //
 // In case of network, parsing or io error (non http related) it will return ClientError.
 //
 // In case of an http related error (>400 status code) it will return ClientHttpError along with returned status code.
+//
+// In case there's no codec for response's Content-Type it will return UnsupportedMediaTypeError.
+//
+// In case any of the timeouts expires it will return TimeoutError with the budget that expired.
 func (c *Client) Delete(ctx context.Context, url string, responseBody interface{}) error {
-	method := "DELETE"
-	request, err := c.createRequest(ctx, method, url, nil)
-	if err != nil {
-		return err
-	}
+	return c.execute(ctx, "DELETE", url, nil, responseBody)
+}
 
-	response, err := c.executeWithRetry(request)
-	if err != nil {
-		return err
-	}
+// Runs HEAD HTTP query for provided url and returns metadata of the response.
+//
+// Errors are the same as the ones returned by Get.
+func (c *Client) Head(ctx context.Context, url string) (*Response, error) {
+	return c.Do(ctx, corehttp.MethodHead, url, nil, nil)
+}
 
-	return readResponse(response, err, url, responseBody)
+// Runs PUT HTTP query for provided url, requestBody will be serialized by the codec of ClientConfig.ContentType,
+// responseBody (pointer) will be written by the codec matching response's Content-Type (JSON by default).
+//
+// Errors are the same as the ones returned by Post.
+func (c *Client) Put(ctx context.Context, url string, requestBody interface{}, responseBody interface{}) error {
+	return c.execute(ctx, corehttp.MethodPut, url, requestBody, responseBody)
 }
 
-// Runs GET HTTP query for provided url, responseBody (pointer) will be written by json.Unmarshal.
+// Runs POST HTTP query for provided url, requestBody will be serialized by the codec of ClientConfig.ContentType,
+// responseBody (pointer) will be written by the codec matching response's Content-Type (JSON by default).
 //
 // In case of network, parsing or io error (non http related) it will return ClientError.
 //
 // In case of an http related error (>400 status code) it will return ClientHttpError along with returned status code.
+//
+// In case there's no codec for response's Content-Type it will return UnsupportedMediaTypeError.
+//
+// In case any of the timeouts expires it will return TimeoutError with the budget that expired.
 func (c *Client) Post(ctx context.Context, url string, requestBody interface{}, responseBody interface{}) error {
-	method := "POST"
+	return c.execute(ctx, "POST", url, requestBody, responseBody)
+}
+
+// Runs HTTP query with provided method for provided url, requestBody (if not nil) will be serialized by the codec
+// of ClientConfig.ContentType, responseBody (pointer) will be written by the codec matching response's Content-Type.
+//
+// Along with the error it returns metadata of the response: status, headers, number of attempts, duration
+// and the final url. It's returned whenever a response arrived, also along with ClientHttpError of a 4xx or 5xx
+// status, and it's nil only when no response was received (e.g. network errors and timeouts).
+//
+// Errors are the same as the ones returned by Get, Delete and Post.
+func (c *Client) Do(ctx context.Context, method string, url string, requestBody interface{}, responseBody interface{}) (*Response, error) {
+	return c.do(ctx, method, url, requestBody, responseBody)
+}
+
+// Stops background work of the Client such as health checks of the endpoints, unless it was carried over
+// by Reconfigure. It's safe to call it more than once
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		if c.endpoints != nil {
+			c.endpoints.release()
+		}
+	})
+}
+
+// Returns a snapshot of the Client's counters
+func (c *Client) Metrics() Metrics {
+	return c.metrics.snapshot()
+}
+
+func (c *Client) execute(ctx context.Context, method string, url string, requestBody interface{}, responseBody interface{}) error {
+	_, err := c.Do(ctx, method, url, requestBody, responseBody)
+	return err
+}
+
+func (c *Client) do(ctx context.Context, method string, url string, requestBody interface{}, responseBody interface{}) (response *Response, err error) {
+	defer func() { c.metrics.routeFinished(routeOf(ctx), err) }()
+	startTime := c.clock.Now()
+	c.metrics.request()
 	request, err := c.createRequest(ctx, method, url, requestBody)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var result *fetched
+	if c.coalescer != nil && method == corehttp.MethodGet {
+		result, err = c.coalescer.do(ctx, request, c.fetch)
+	} else {
+		result, err = c.fetch(ctx, request)
+	}
+	if result == nil {
+		return nil, err
 	}
 
-	response, err := c.executeWithRetry(request)
+	response = newResponse(result, c.clock.Now().Sub(startTime))
 	if err != nil {
-		return err
+		return response, err
 	}
+	return response, c.decodeResponse(result, url, responseBody)
+}
 
-	return readResponse(response, err, url, responseBody)
+// Response which body was already read
+type fetched struct {
+	statusCode int
+	header     corehttp.Header
+	body       []byte
+	attempts   int
+	url        *neturl.URL
 }
 
-func (c *Client) createRequest(context context.Context, method string, url string, requestBody interface{}) (resp *corehttp.Request, err error) {
-	marshaledBody, err := json.Marshal(requestBody)
+// Runs the request with retries within the total timeout and reads its body.
+// Responses with a 4xx or 5xx status are read as well and returned along with ClientHttpError
+func (c *Client) fetch(ctx context.Context, request *corehttp.Request) (*fetched, error) {
+	operationCtx, cancel := c.withTotalTimeout(ctx)
+	defer cancel()
 
-	if err != nil {
-		return nil, &ClientError{Message: "body parse error", Url: url, Err: err}
+	url := request.URL.String()
+	response, attempts, err := c.executeWithRetry(request.WithContext(operationCtx))
+	var httpError *ClientHttpError
+	if err != nil && (response == nil || !errors.As(err, &httpError)) {
+		closeResponse(response)
+		return nil, c.timeoutError(ctx, operationCtx, url, err)
 	}
 
-	req, err := corehttp.NewRequestWithContext(context, method, url, bytes.NewBuffer(marshaledBody))
+	result, readErr := c.readResponse(response, url)
+	if readErr != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, c.timeoutError(ctx, operationCtx, url, readErr)
+	}
+	result.attempts = attempts
+	return result, err
+}
+
+func (c *Client) createRequest(context context.Context, method string, url string, requestBody interface{}) (resp *corehttp.Request, err error) {
+	var body io.Reader
+	var compressed bool
+	if requestBody != nil {
+		marshaledBody, err := c.codecs.request.Marshal(requestBody)
+		if err != nil {
+			return nil, &ClientError{Message: "body parse error", Url: url, Err: err}
+		}
+		marshaledBody, compressed, err = c.compression.compress(marshaledBody)
+		if err != nil {
+			return nil, &ClientError{Message: "body compression error", Url: url, Err: err}
+		}
+		body = bytes.NewBuffer(marshaledBody)
+	}
+
+	req, err := corehttp.NewRequestWithContext(context, method, url, body)
 	if err != nil {
 		return nil, &ClientError{Message: "network error", Url: url, Err: err}
 	}
 	c.setHeaders(req)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", c.codecs.accept)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", c.codecs.request.ContentType())
+	}
+	c.compression.setHeaders(req)
+	if compressed {
+		req.Header.Set("Content-Encoding", GzipEncoding)
+	}
 	return req, nil
 }
 
@@ -145,63 +424,250 @@ func (c *Client) setHeaders(req *corehttp.Request) {
 	for key, value := range c.headers {
 		req.Header.Set(key, value)
 	}
+	for key, value := range requestHeaders(req.Context()) {
+		req.Header.Set(key, value)
+	}
+}
+
+type requestHeadersKey struct{}
+
+// Returns a copy of ctx with headers which are set on requests sent with it, on top of ClientConfig.Headers,
+// e.g. conditional If-None-Match. Headers already carried by ctx are kept unless overridden
+func WithHeaders(ctx context.Context, headers Headers) context.Context {
+	merged := Headers{}
+	for key, value := range requestHeaders(ctx) {
+		merged[key] = value
+	}
+	for key, value := range headers {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, requestHeadersKey{}, merged)
+}
+
+func requestHeaders(ctx context.Context) Headers {
+	headers, _ := ctx.Value(requestHeadersKey{}).(Headers)
+	return headers
 }
 
-func (c *Client) logNewRequest(method string, url string) {
+func (c *Client) logNewRequest(method string, url string, route string) {
 	if c.logging {
-		log.Printf("Outgoing request to [%s][%s] \n", method, url)
+		log.Printf("Outgoing request to [%s][%s]%s \n", method, url, routeLabel(route))
 	}
 }
 
-func (c *Client) logFinishedRequest(method string, url string, elapsed time.Duration, response *corehttp.Response) {
+func (c *Client) logFinishedRequest(method string, url string, route string, elapsed time.Duration, response *corehttp.Response) {
 	if !c.logging {
 		return
 	}
 	if response != nil && response.StatusCode >= 400 {
-		log.Printf("Outgoing request to [%s] [%s] failed with status [%d] in [%s] \n", method, url, response.StatusCode, elapsed.String())
+		log.Printf("Outgoing request to [%s] [%s]%s failed with status [%d] in [%s] \n", method, url, routeLabel(route), response.StatusCode, elapsed.String())
 	} else {
-		log.Printf("Outgoing request to [%s] [%s] completed in [%s] \n", method, url, elapsed.String())
+		log.Printf("Outgoing request to [%s] [%s]%s completed in [%s] \n", method, url, routeLabel(route), elapsed.String())
 	}
 }
 
-func (c *Client) executeWithRetry(request *corehttp.Request) (*corehttp.Response, error) {
-	response, err := c.retry.Execute(func() (*corehttp.Response, error) {
-		startTime := time.Now()
-		c.logNewRequest(request.Method, request.URL.String())
-		response, err := c.client.Do(request)
-		c.logFinishedRequest(request.Method, request.URL.String(), time.Now().Sub(startTime), response)
+func routeLabel(route string) string {
+	if route == "" {
+		return ""
+	}
+	return fmt.Sprintf(" of route [%s]", route)
+}
+
+// Returns the last response along with the number of attempts made
+func (c *Client) executeWithRetry(request *corehttp.Request) (*corehttp.Response, int, error) {
+	var previousResponse *corehttp.Response
+	var previousEndpoint *endpoint
+	attempts := 0
+	url := request.URL.String()
+	response, err := c.retry.ExecuteWithContext(request.Context(), func() (*corehttp.Response, error) {
+		closeResponse(previousResponse)
+		var result sent
+		if c.hedger != nil && request.Method == corehttp.MethodGet && extraTimeout(request.Context()) == 0 {
+			c.metrics.attempt()
+			attempts++
+			result = c.hedger.do(c, request, previousEndpoint)
+		} else {
+			attempt, picked, err := c.prepare(request.Context(), request, previousEndpoint)
+			if err != nil {
+				return nil, err
+			}
+			c.metrics.attempt()
+			attempts++
+			result = c.sendPrepared(attempt, picked)
+		}
+		previousEndpoint, url = result.endpoint, result.url
+		response, err := result.response, result.err
+		previousResponse = response
 		if shouldRetry(response, err) {
 			return response, &retry.RetryableError{Err: err}
 		}
+		if err == nil && response.StatusCode >= 400 {
+			// Not worth retrying, yet it's not a success for the hooks of the retries
+			return response, &ClientHttpError{Url: url, StatusCode: response.StatusCode}
+		}
 		return response, err
 	})
+	c.metrics.retried(err)
 
 	if response != nil && response.StatusCode >= 400 {
-		return response, &ClientHttpError{Url: request.URL.String(), StatusCode: response.StatusCode}
+		return response, attempts, &ClientHttpError{Url: url, StatusCode: response.StatusCode}
 	}
 
 	if err != nil {
-		return response, &ClientError{Message: "network error", Url: request.URL.String(), Err: err}
+		return response, attempts, &ClientError{Message: "network error", Url: url, Err: err}
 	}
 
-	return response, nil
+	return response, attempts, nil
+}
+
+// Request of an attempt along with its outcome, endpoint is nil unless the request was resolved against one
+type sent struct {
+	url      string
+	endpoint *endpoint
+	response *corehttp.Response
+	err      error
+}
+
+// Copies the request for an attempt and resolves it against an endpoint other than avoid when possible
+func (c *Client) prepare(ctx context.Context, request *corehttp.Request, avoid *endpoint) (*corehttp.Request, *endpoint, error) {
+	attempt, err := newAttempt(ctx, request)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.endpoints == nil {
+		return attempt, nil, nil
+	}
+	picked, err := c.endpoints.resolve(attempt, avoid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attempt, picked, nil
+}
+
+// Sends the prepared request and reports its outcome to the endpoint it was resolved against
+func (c *Client) sendPrepared(attempt *corehttp.Request, picked *endpoint) sent {
+	url := attempt.URL.String()
+	route := routeOf(attempt.Context())
+	startTime := c.clock.Now()
+	c.logNewRequest(attempt.Method, url, route)
+	response, err := c.send(attempt)
+	elapsed := c.clock.Now().Sub(startTime)
+	c.logFinishedRequest(attempt.Method, url, route, elapsed, response)
+	if c.endpoints != nil && attempt.Context().Err() == nil {
+		c.endpoints.report(picked, elapsed, response, err)
+	}
+	return sent{url: url, endpoint: picked, response: response, err: err}
+}
+
+func (c *Client) send(request *corehttp.Request) (*corehttp.Response, error) {
+	if extra := extraTimeout(request.Context()); extra > 0 {
+		// Shares the transport, so the request still uses the connection pool
+		client := *c.client
+		if client.Timeout > 0 {
+			client.Timeout += extra
+		}
+		return client.Do(request)
+	}
+	return c.client.Do(request)
+}
+
+// Every attempt gets a fresh copy of the request body, as the previous one was already consumed
+func newAttempt(ctx context.Context, request *corehttp.Request) (*corehttp.Request, error) {
+	attempt := request.Clone(ctx)
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		attempt.Body = body
+	}
+	return attempt, nil
+}
+
+func closeResponse(response *corehttp.Response) {
+	if response != nil && response.Body != nil {
+		io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+	}
+}
+
+func closeAll(closers []io.Closer) {
+	for _, closer := range closers {
+		closer.Close()
+	}
 }
 
 func shouldRetry(response *corehttp.Response, err error) bool {
 	return err != nil || response == nil || response.StatusCode >= 500
 }
 
-func readResponse(response *corehttp.Response, err error, url string, responseBody interface{}) error {
-	buffer, err := ioutil.ReadAll(response.Body)
+func (c *Client) readResponse(response *corehttp.Response, url string) (*fetched, error) {
 	defer response.Body.Close()
+	reader, closers, err := c.compression.decoder(response)
+	defer closeAll(closers)
+	if err != nil {
+		return nil, &ClientError{Message: "decoding error", Url: url, Err: err}
+	}
 
+	buffer, err := c.compression.readAll(reader)
+	if errors.Is(err, DecodedSizeExceededError) {
+		return nil, &ClientError{Message: "decoding error", Url: url, Err: err}
+	}
 	if err != nil {
-		return &ClientError{Message: "io error", Url: url, Err: err}
+		return nil, &ClientError{Message: "io error", Url: url, Err: err}
 	}
+	return &fetched{statusCode: response.StatusCode, header: response.Header, body: buffer, url: finalUrl(response, url)}, nil
+}
 
-	err = json.Unmarshal(buffer, responseBody)
+// Url of the request which produced the response, differs from the requested one after redirects
+func finalUrl(response *corehttp.Response, url string) *neturl.URL {
+	if response.Request != nil && response.Request.URL != nil {
+		return response.Request.URL
+	}
+	parsed, _ := neturl.Parse(url)
+	return parsed
+}
+
+// Decodes the body into responseBody, nothing is decoded when responseBody is nil or the response has no content
+// (204, 205 or an empty body of a non 2xx status such as 304)
+func (c *Client) decodeResponse(result *fetched, url string, responseBody interface{}) error {
+	if responseBody == nil || !hasContent(result) {
+		return nil
+	}
+	if len(result.body) == 0 {
+		return &ClientError{Message: "parsing error", Url: url, Err: EmptyBodyError}
+	}
+
+	contentType := result.header.Get("Content-Type")
+	codec, ok := c.codecs.forResponse(contentType)
+	if !ok {
+		return &UnsupportedMediaTypeError{Url: url, ContentType: contentType}
+	}
+	if isGenericMediaType(contentType) {
+		switch c.typeCheck {
+		case WarnContentTypeCheck:
+			if c.logging {
+				log.Printf("Response from [%s] has unexpected Content-Type [%s], decoding it as [%s] \n", url, contentType, codec.ContentType())
+			}
+		case StrictContentTypeCheck:
+			return &UnsupportedMediaTypeError{Url: url, ContentType: contentType}
+		}
+	}
+
+	err := codec.Unmarshal(result.body, responseBody)
 	if err != nil {
 		return &ClientError{Message: "parsing error", Url: url, Err: err}
 	}
 	return nil
 }
+
+func hasContent(result *fetched) bool {
+	switch {
+	case result.statusCode == corehttp.StatusNoContent || result.statusCode == corehttp.StatusResetContent:
+		return false
+	case result.statusCode < 200 || result.statusCode >= 300:
+		return len(result.body) > 0
+	default:
+		return true
+	}
+}
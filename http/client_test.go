@@ -461,6 +461,211 @@ func TestClient_PostWithDialError(t *testing.T) {
 	assert.Equal(t, 0, callCount["/"])
 }
 
+func TestClient_DeleteWithNoContent(t *testing.T) {
+	t.Logf("Given HTTP server returning 204 status")
+	callCount := make(map[string]int)
+	server := httptest.NewServer(requestHandler(204, &callCount))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling DELETE with and without response body")
+	dummyResponse := DummyResponse{Title: "Jan"}
+	err := client.Delete(context.Background(), server.URL, &dummyResponse)
+	errWithoutBody := client.Delete(context.Background(), server.URL, nil)
+
+	t.Logf("Should return no error and leave DummyResponse untouched")
+	assert.NoError(t, err)
+	assert.NoError(t, errWithoutBody)
+	assert.Equal(t, DummyResponse{Title: "Jan"}, dummyResponse)
+	assert.Equal(t, 2, callCount["/"])
+}
+
+func TestClient_GetWithEmptyBody(t *testing.T) {
+	t.Logf("Given HTTP server returning 200 status with empty body")
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "application/json")
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling GET with and without response body")
+	var dummyResponse DummyResponse
+	err := client.Get(context.Background(), server.URL, &dummyResponse)
+	errWithoutBody := client.Get(context.Background(), server.URL, nil)
+
+	t.Logf("Should return EmptyBodyError only when the body is expected")
+	var clientError *ClientError
+	assert.True(t, errors.As(err, &clientError))
+	assert.Equal(t, "parsing error", clientError.Message)
+	assert.True(t, errors.Is(err, EmptyBodyError))
+	assert.NoError(t, errWithoutBody)
+}
+
+func TestClient_ResponsesWithoutContentByDefault(t *testing.T) {
+	testCases := []struct {
+		Method     string
+		StatusCode int
+	}{
+		{Method: "GET", StatusCode: 204},
+		{Method: "PUT", StatusCode: 205},
+		{Method: "GET", StatusCode: 304},
+		{Method: "DELETE", StatusCode: 200},
+		{Method: "HEAD", StatusCode: 200},
+	}
+	for _, testCase := range testCases {
+		t.Logf("Given HTTP server returning %d status without body", testCase.StatusCode)
+		server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			res.WriteHeader(testCase.StatusCode)
+		}))
+
+		t.Logf("And given Client with default settings")
+		client, _ := NewClient(ClientConfig{Timeout: time.Second, Retries: validClientConfig.Retries})
+
+		t.Logf("When calling %s expecting a response body", testCase.Method)
+		dummyResponse := DummyResponse{Title: "Jan"}
+		var target interface{} = &dummyResponse
+		if testCase.Method == "HEAD" {
+			target = nil
+		}
+		response, err := client.Do(context.Background(), testCase.Method, server.URL, nil, target)
+
+		if testCase.StatusCode == 200 && testCase.Method != "HEAD" {
+			t.Logf("Should return EmptyBodyError")
+			assert.True(t, errors.Is(err, EmptyBodyError))
+		} else {
+			t.Logf("Should return the response and leave the body untouched")
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.StatusCode, response.StatusCode)
+		}
+		assert.Equal(t, DummyResponse{Title: "Jan"}, dummyResponse)
+		server.Close()
+	}
+}
+
+func TestClient_Head(t *testing.T) {
+	t.Logf("Given HTTP server returning 200 status with ETag")
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		method = req.Method
+		res.Header().Set("ETag", `"v1"`)
+		res.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling HEAD")
+	response, err := client.Head(context.Background(), server.URL)
+
+	t.Logf("Should return response's metadata")
+	assert.NoError(t, err)
+	assert.Equal(t, "HEAD", method)
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, `"v1"`, response.Header.Get("ETag"))
+}
+
+func TestClient_GetWithConditionalHeaders(t *testing.T) {
+	t.Logf("Given HTTP server returning 304 status when If-None-Match matches its ETag")
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			res.WriteHeader(304)
+			return
+		}
+		res.Header().Set("ETag", `"v1"`)
+		res.Write([]byte(`{"id":1,"title":"Jan"}`))
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling GET with If-None-Match header carried by the context")
+	ctx := WithHeaders(context.Background(), Headers{"If-None-Match": `"v1"`})
+	dummyResponse := DummyResponse{Title: "cached"}
+	response, err := client.Do(ctx, "GET", server.URL, nil, &dummyResponse)
+
+	t.Logf("Should return 304 status and leave the response body untouched")
+	assert.NoError(t, err)
+	assert.Equal(t, 304, response.StatusCode)
+	assert.Equal(t, DummyResponse{Title: "cached"}, dummyResponse)
+}
+
+func TestClient_Put(t *testing.T) {
+	t.Logf("Given HTTP server returning 200 status")
+	callCount := make(map[string]int)
+	server := httptest.NewServer(requestHandlerWithBody(200, &callCount, DummyResponse{Title: "Jan", Id: 1}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling PUT")
+	var dummyResponse DummyResponse
+	err := client.Put(context.Background(), server.URL, DummyRequest{Title: "Jan"}, &dummyResponse)
+
+	t.Logf("Should return DummyResponse")
+	assert.NoError(t, err)
+	assert.Equal(t, DummyResponse{Title: "Jan", Id: 1}, dummyResponse)
+	assert.Equal(t, 1, callCount["/"])
+}
+
+func TestClient_GetWithExhaustedRetryBudget(t *testing.T) {
+	t.Logf("Given HTTP server returning 500 status")
+	callCount := make(map[string]int)
+	server := httptest.NewServer(requestHandler(500, &callCount))
+	defer server.Close()
+
+	t.Logf("And given Client with retry budget allowing a single retry")
+	budget, _ := retry.NewBudget(retry.BudgetConfig{MinPerSecond: 0.1, Window: 10 * time.Second})
+	config := validClientConfig
+	config.Retries.Budget = budget
+	client, _ := NewClient(config)
+
+	t.Logf("When calling GET twice")
+	firstErr := client.Get(context.Background(), server.URL, &DummyResponse{})
+	secondErr := client.Get(context.Background(), server.URL, &DummyResponse{})
+
+	t.Logf("Should retry only once and report skipped retries")
+	assert.EqualError(t, firstErr, (&ClientHttpError{Url: server.URL, StatusCode: 500}).Error())
+	assert.EqualError(t, secondErr, (&ClientHttpError{Url: server.URL, StatusCode: 500}).Error())
+	assert.Equal(t, 3, callCount["/"])
+	assert.Equal(t, int64(2), client.Metrics().RetriesSkipped)
+
+	t.Logf("And should keep counting skipped retries across Reconfigure")
+	reconfigured, err := client.Reconfigure(config)
+	assert.NoError(t, err)
+	_ = reconfigured.Get(context.Background(), server.URL, &DummyResponse{})
+	assert.Equal(t, int64(3), reconfigured.Metrics().RetriesSkipped)
+}
+
+func TestClient_GetWithClientErrorReportsGiveUp(t *testing.T) {
+	t.Logf("Given HTTP server returning 404 status")
+	callCount := make(map[string]int)
+	server := httptest.NewServer(requestHandler(404, &callCount))
+	defer server.Close()
+
+	t.Logf("And given Client with retry hooks")
+	var successes, giveUps []retry.Attempt
+	config := validClientConfig
+	config.Retries.OnSuccess = func(attempt retry.Attempt) { successes = append(successes, attempt) }
+	config.Retries.OnGiveUp = func(attempt retry.Attempt) { giveUps = append(giveUps, attempt) }
+	client, _ := NewClient(config)
+
+	t.Logf("When calling GET")
+	err := client.Get(context.Background(), server.URL, &DummyResponse{})
+
+	t.Logf("Should not retry and report the attempt as given up")
+	assert.EqualError(t, err, (&ClientHttpError{Url: server.URL, StatusCode: 404}).Error())
+	assert.Equal(t, 1, callCount["/"])
+	assert.Empty(t, successes)
+	assert.Equal(t, []retry.Attempt{{Number: 1, StatusCode: 404, Err: &ClientHttpError{Url: server.URL, StatusCode: 404}}}, giveUps)
+}
+
 func requestHandler(statusCode int, callCount *map[string]int) http.HandlerFunc {
 	return requestHandlerWithBody(statusCode, callCount, nil)
 }
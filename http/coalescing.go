@@ -0,0 +1,158 @@
+package http
+
+import (
+	"context"
+	corehttp "net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request headers which are always part of the deduplication key
+var defaultCoalescingHeaders = []string{"Accept", "Accept-Encoding", "Authorization"}
+
+// Configures deduplication of concurrent, identical GET requests.
+//
+// Requests with the same url and the same values of the key headers are sent only once,
+// every caller decodes its own copy of the shared response.
+type CoalescingConfig struct {
+	Enabled bool
+	// Additional request headers which values are part of the deduplication key,
+	// Accept, Accept-Encoding and Authorization are always included
+	Headers []string
+}
+
+// Shared request, waited on by one or more callers
+type flight struct {
+	done    chan struct{}
+	result  *fetched
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+type coalescer struct {
+	mutex   sync.Mutex
+	flights map[string]*flight
+	headers []string
+	metrics *metrics
+}
+
+func newCoalescer(config CoalescingConfig, metrics *metrics) *coalescer {
+	if !config.Enabled {
+		return nil
+	}
+	return &coalescer{
+		flights: map[string]*flight{},
+		headers: append(append([]string{}, defaultCoalescingHeaders...), config.Headers...),
+		metrics: metrics,
+	}
+}
+
+// Joins the flight of an identical request or starts a new one.
+//
+// The shared request runs with its own context, so cancelling one of the callers does not affect the others,
+// it is cancelled only once all of the callers are gone. The context keeps values of the caller which started
+// the flight (e.g. WithHeaders or WithExtraTimeout), but not its deadline.
+func (c *coalescer) do(ctx context.Context, request *corehttp.Request,
+	fetch func(context.Context, *corehttp.Request) (*fetched, error)) (*fetched, error) {
+	key := c.key(request)
+
+	c.mutex.Lock()
+	current, ok := c.flights[key]
+	if ok {
+		current.waiters++
+		c.mutex.Unlock()
+		c.metrics.coalesce()
+	} else {
+		flightCtx, cancel := context.WithCancel(valuesOnly{ctx})
+		current = &flight{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		c.flights[key] = current
+		c.mutex.Unlock()
+		go c.run(flightCtx, key, current, request, fetch)
+	}
+
+	select {
+	case <-current.done:
+		return current.result, current.err
+	case <-ctx.Done():
+		c.leave(key, current)
+		// Same as a request which isn't coalesced, errors of the caller's own context are returned as they are
+		return nil, ctx.Err()
+	}
+}
+
+func (c *coalescer) run(ctx context.Context, key string, current *flight, request *corehttp.Request,
+	fetch func(context.Context, *corehttp.Request) (*fetched, error)) {
+	current.result, current.err = fetch(ctx, request.WithContext(ctx))
+
+	c.mutex.Lock()
+	if c.flights[key] == current {
+		delete(c.flights, key)
+	}
+	c.mutex.Unlock()
+	current.cancel()
+	close(current.done)
+}
+
+// Context with values of the wrapped one, which is never done, so it outlives the caller which started the flight
+type valuesOnly struct {
+	values context.Context
+}
+
+func (valuesOnly) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (valuesOnly) Done() <-chan struct{} {
+	return nil
+}
+
+func (valuesOnly) Err() error {
+	return nil
+}
+
+func (v valuesOnly) Value(key interface{}) interface{} {
+	return v.values.Value(key)
+}
+
+func (c *coalescer) leave(key string, current *flight) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	current.waiters--
+	if current.waiters > 0 {
+		return
+	}
+	current.cancel()
+	if c.flights[key] == current {
+		delete(c.flights, key)
+	}
+}
+
+func (c *coalescer) key(request *corehttp.Request) string {
+	var key strings.Builder
+	key.WriteString(request.Method)
+	key.WriteString(" ")
+	key.WriteString(request.URL.String())
+	for _, header := range c.headers {
+		key.WriteString("\n")
+		key.WriteString(corehttp.CanonicalHeaderKey(header))
+		key.WriteString(": ")
+		key.WriteString(strings.Join(request.Header.Values(header), ","))
+	}
+	// Headers set with WithHeaders are specific to the caller, so they're always part of the key
+	headers := requestHeaders(request.Context())
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, corehttp.CanonicalHeaderKey(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key.WriteString("\n")
+		key.WriteString(name)
+		key.WriteString(": ")
+		key.WriteString(request.Header.Get(name))
+	}
+	return key.String()
+}
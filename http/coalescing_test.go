@@ -0,0 +1,193 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_GetWithCoalescing(t *testing.T) {
+	t.Logf("Given HTTP server responding once released")
+	var callCount int32
+	release := make(chan struct{})
+	server := httptest.NewServer(blockingRequestHandler(release, &callCount, nil))
+	defer server.Close()
+
+	t.Logf("And given Client with coalescing")
+	config := validClientConfig
+	config.Coalescing = CoalescingConfig{Enabled: true}
+	client, _ := NewClient(config)
+
+	t.Logf("When calling GET from 10 goroutines at the same time")
+	responses := make([]DummyResponse, 10)
+	errs := make([]error, 10)
+	var group sync.WaitGroup
+	for i := range responses {
+		group.Add(1)
+		go func(i int) {
+			defer group.Done()
+			errs[i] = client.Get(context.Background(), server.URL, &responses[i])
+		}(i)
+	}
+	waitFor(t, func() bool { return client.Metrics().Coalesced == 9 })
+	close(release)
+	group.Wait()
+
+	t.Logf("Should call the server once and return DummyResponse to every caller")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&callCount))
+	for i := range responses {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, DummyResponse{Title: "Jan", Id: 1}, responses[i])
+	}
+}
+
+func TestClient_GetWithCoalescingAndDifferentUrls(t *testing.T) {
+	t.Logf("Given HTTP server")
+	callCount := make(map[string]int)
+	server := httptest.NewServer(requestHandlerWithBody(200, &callCount, DummyResponse{Title: "Jan", Id: 1}))
+	defer server.Close()
+
+	t.Logf("And given Client with coalescing")
+	config := validClientConfig
+	config.Coalescing = CoalescingConfig{Enabled: true}
+	client, _ := NewClient(config)
+
+	t.Logf("When calling GET for two different urls")
+	var first, second DummyResponse
+	firstErr := client.Get(context.Background(), server.URL+"/1", &first)
+	secondErr := client.Get(context.Background(), server.URL+"/2", &second)
+
+	t.Logf("Should call the server for each of them")
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr)
+	assert.Equal(t, 1, callCount["/1"])
+	assert.Equal(t, 1, callCount["/2"])
+	assert.Equal(t, int64(0), client.Metrics().Coalesced)
+}
+
+func TestClient_GetWithCoalescingAndCancelledCaller(t *testing.T) {
+	t.Logf("Given HTTP server responding once released")
+	var callCount int32
+	release := make(chan struct{})
+	cancelled := make(chan struct{}, 1)
+	server := httptest.NewServer(blockingRequestHandler(release, &callCount, cancelled))
+	defer server.Close()
+
+	t.Logf("And given Client with coalescing")
+	config := validClientConfig
+	config.Coalescing = CoalescingConfig{Enabled: true}
+	client, _ := NewClient(config)
+
+	t.Logf("When calling GET from two callers and cancelling the first one")
+	ctx, cancel := context.WithCancel(context.Background())
+	var firstErr, secondErr error
+	var second DummyResponse
+	var group sync.WaitGroup
+	group.Add(2)
+	go func() {
+		defer group.Done()
+		var first DummyResponse
+		firstErr = client.Get(ctx, server.URL, &first)
+	}()
+	waitFor(t, func() bool { return atomic.LoadInt32(&callCount) == 1 })
+	go func() {
+		defer group.Done()
+		secondErr = client.Get(context.Background(), server.URL, &second)
+	}()
+	waitFor(t, func() bool { return client.Metrics().Coalesced == 1 })
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	group.Wait()
+
+	t.Logf("Should return context's error to the first caller and DummyResponse to the second one")
+	assert.Equal(t, context.Canceled, firstErr)
+	assert.NoError(t, secondErr)
+	assert.Equal(t, DummyResponse{Title: "Jan", Id: 1}, second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&callCount))
+	assert.Equal(t, 0, len(cancelled))
+}
+
+func TestClient_GetWithCoalescingAndAllCallersCancelled(t *testing.T) {
+	t.Logf("Given HTTP server responding once released")
+	var callCount int32
+	release := make(chan struct{})
+	defer close(release)
+	cancelled := make(chan struct{}, 1)
+	server := httptest.NewServer(blockingRequestHandler(release, &callCount, cancelled))
+	defer server.Close()
+
+	t.Logf("And given Client with coalescing")
+	config := validClientConfig
+	config.Coalescing = CoalescingConfig{Enabled: true}
+	client, _ := NewClient(config)
+
+	t.Logf("When calling GET and cancelling it")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	var dummyResponse DummyResponse
+	err := client.Get(ctx, server.URL, &dummyResponse)
+
+	t.Logf("Should cancel the shared request")
+	assert.True(t, errors.Is(err, context.Canceled))
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("shared request was not cancelled")
+	}
+}
+
+func TestClient_GetWithCoalescingKeepsContextValues(t *testing.T) {
+	t.Logf("Given HTTP server responding after 200ms")
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		res.Write([]byte(`{"id":1,"title":"Jan"}`))
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client with coalescing and timeout of 100ms")
+	config := validClientConfig
+	config.Timeout = 100 * time.Millisecond
+	config.Retries.MaxRetries = 1
+	config.Coalescing = CoalescingConfig{Enabled: true}
+	client, _ := NewClient(config)
+
+	t.Logf("When calling GET with extra timeout of 500ms")
+	var dummyResponse DummyResponse
+	err := client.Get(WithExtraTimeout(context.Background(), 500*time.Millisecond), server.URL, &dummyResponse)
+
+	t.Logf("Should send the shared request with the extra timeout")
+	assert.NoError(t, err)
+	assert.Equal(t, DummyResponse{Title: "Jan", Id: 1}, dummyResponse)
+}
+
+// Responds once release is closed, reports cancelled requests to the cancelled channel
+func blockingRequestHandler(release chan struct{}, callCount *int32, cancelled chan struct{}) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(callCount, 1)
+		select {
+		case <-release:
+			res.Write([]byte(`{"id":1,"title":"Jan"}`))
+		case <-req.Context().Done():
+			if cancelled != nil {
+				cancelled <- struct{}{}
+			}
+		}
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,209 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+	"mime"
+	"reflect"
+	"strings"
+)
+
+// Media types of the codecs shipped with the Client
+const (
+	JSONContentType    = "application/json"
+	XMLContentType     = "application/xml"
+	MsgpackContentType = "application/msgpack"
+	FormContentType    = "application/x-www-form-urlencoded"
+)
+
+// Serializes request bodies and deserializes response bodies of a single media type.
+//
+// The Client picks the codec for a response by its Content-Type header.
+type Codec interface {
+	// Media type handled by the codec, e.g. application/json
+	ContentType() string
+	Marshal(value interface{}) ([]byte, error)
+	Unmarshal(data []byte, value interface{}) error
+}
+
+// Codec backed by encoding/json, used by default
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return JSONContentType
+}
+
+func (JSONCodec) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec) Unmarshal(data []byte, value interface{}) error {
+	return json.Unmarshal(data, value)
+}
+
+// Codec backed by encoding/xml.
+//
+// Slices are decoded from children of the root element, e.g. <items><item/><item/></items>
+type XMLCodec struct{}
+
+func (XMLCodec) ContentType() string {
+	return XMLContentType
+}
+
+func (XMLCodec) Marshal(value interface{}) ([]byte, error) {
+	return xml.Marshal(value)
+}
+
+func (XMLCodec) Unmarshal(data []byte, value interface{}) error {
+	target := reflect.ValueOf(value)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Slice {
+		return xml.Unmarshal(data, value)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				depth++
+				continue
+			}
+			item := reflect.New(target.Elem().Type().Elem())
+			if err := decoder.DecodeElement(item.Interface(), &element); err != nil {
+				return err
+			}
+			target.Elem().Set(reflect.Append(target.Elem(), item.Elem()))
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// MessagePack codec, field names are taken from json tags so the same models can be used with JSONCodec
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) ContentType() string {
+	return MsgpackContentType
+}
+
+func (MsgpackCodec) Marshal(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, value interface{}) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(value)
+}
+
+// Alternative media types of the shipped codecs
+var mediaTypeAliases = map[string]string{
+	"text/json":               JSONContentType,
+	"text/xml":                XMLContentType,
+	"application/x-msgpack":   MsgpackContentType,
+	"application/vnd.msgpack": MsgpackContentType,
+}
+
+// Media types which say nothing about the content, those are decoded with the request codec
+var genericMediaTypes = map[string]bool{
+	"":                         true,
+	"text/plain":               true,
+	"application/octet-stream": true,
+}
+
+// Controls how responses with a generic Content-Type (missing, text/plain, application/octet-stream) are decoded
+type ContentTypeCheck int
+
+const (
+	// Logs a warning when ClientConfig.Logging is enabled and decodes the body with the codec of ClientConfig.ContentType,
+	// it's the default
+	WarnContentTypeCheck ContentTypeCheck = iota
+	// Decodes the body with the codec of ClientConfig.ContentType
+	LenientContentTypeCheck
+	// Returns UnsupportedMediaTypeError instead of decoding the body
+	StrictContentTypeCheck
+)
+
+func isGenericMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = ""
+	}
+	return genericMediaTypes[mediaType]
+}
+
+type codecs struct {
+	request     Codec
+	byMediaType map[string]Codec
+	accept      string
+}
+
+// Registers shipped codecs along with the provided ones (which take precedence),
+// returns UnknownContentTypeError if none of them handles contentType
+func newCodecs(contentType string, extra []Codec) (*codecs, error) {
+	all := append([]Codec{JSONCodec{}, XMLCodec{}, MsgpackCodec{}, FormCodec{}}, extra...)
+	byMediaType := map[string]Codec{}
+	for _, codec := range all {
+		byMediaType[codec.ContentType()] = codec
+	}
+
+	if contentType == "" {
+		contentType = JSONContentType
+	}
+	request, ok := byMediaType[contentType]
+	if !ok {
+		return nil, UnknownContentTypeError
+	}
+
+	accept := []string{request.ContentType()}
+	listed := map[string]bool{request.ContentType(): true}
+	for _, codec := range all {
+		if mediaType := codec.ContentType(); !listed[mediaType] {
+			listed[mediaType] = true
+			accept = append(accept, mediaType+";q=0.9")
+		}
+	}
+	return &codecs{request: request, byMediaType: byMediaType, accept: strings.Join(accept, ", ")}, nil
+}
+
+// Picks the codec by Content-Type of the response, returns false when there is none for its media type
+func (c *codecs) forResponse(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = ""
+	}
+	if alias, ok := mediaTypeAliases[mediaType]; ok {
+		mediaType = alias
+	}
+	if codec, ok := c.byMediaType[mediaType]; ok {
+		return codec, true
+	}
+
+	switch {
+	case genericMediaTypes[mediaType]:
+		return c.request, true
+	case strings.HasSuffix(mediaType, "+json"):
+		return c.byMediaType[JSONContentType], true
+	case strings.HasSuffix(mediaType, "+xml"):
+		return c.byMediaType[XMLContentType], true
+	default:
+		return nil, false
+	}
+}
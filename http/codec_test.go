@@ -0,0 +1,283 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type CodecDummyResponse struct {
+	Id    int    `json:"id" xml:"id"`
+	Title string `json:"title" xml:"title"`
+}
+
+func TestNewClientWithUnknownContentType(t *testing.T) {
+	t.Logf("Given ClientConfig with text/csv content type")
+	config := validClientConfig
+	config.ContentType = "text/csv"
+
+	t.Logf("When creating Client")
+	client, err := NewClient(config)
+
+	t.Logf("Should return UnknownContentTypeError")
+	assert.Equal(t, UnknownContentTypeError, err)
+	assert.Nil(t, client)
+}
+
+func TestClient_GetWithCodecs(t *testing.T) {
+	expectedResponse := CodecDummyResponse{Title: "Jan", Id: 1}
+	msgpackBody, _ := MsgpackCodec{}.Marshal(expectedResponse)
+
+	testCases := []struct {
+		ContentType string
+		Body        []byte
+	}{
+		{ContentType: "application/json; charset=utf-8", Body: []byte(`{"id":1,"title":"Jan"}`)},
+		{ContentType: "application/problem+json", Body: []byte(`{"id":1,"title":"Jan"}`)},
+		{ContentType: "application/xml", Body: []byte(`<item><id>1</id><title>Jan</title></item>`)},
+		{ContentType: "text/xml; charset=utf-8", Body: []byte(`<item><id>1</id><title>Jan</title></item>`)},
+		{ContentType: "application/msgpack", Body: msgpackBody},
+		{ContentType: "application/x-www-form-urlencoded", Body: []byte(`id=1&title=Jan`)},
+		{ContentType: "text/plain", Body: []byte(`{"id":1,"title":"Jan"}`)},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("Given HTTP server responding with %s", testCase.ContentType)
+		server := httptest.NewServer(contentTypeRequestHandler(testCase.ContentType, testCase.Body))
+
+		t.Logf("And given Client")
+		client, _ := NewClient(validClientConfig)
+
+		t.Logf("When calling GET")
+		var dummyResponse CodecDummyResponse
+		err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+		t.Logf("Should decode the response")
+		assert.NoError(t, err)
+		assert.Equal(t, expectedResponse, dummyResponse)
+		server.Close()
+	}
+}
+
+func TestClient_GetListWithXMLCodec(t *testing.T) {
+	t.Logf("Given HTTP server responding with XML list")
+	server := httptest.NewServer(contentTypeRequestHandler("application/xml",
+		[]byte(`<items><item><id>1</id><title>Jan</title></item><item><id>2</id><title>Anna</title></item></items>`)))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling GET")
+	var dummyResponses []CodecDummyResponse
+	err := client.Get(context.Background(), server.URL, &dummyResponses)
+
+	t.Logf("Should decode every item of the list")
+	assert.NoError(t, err)
+	assert.Equal(t, []CodecDummyResponse{{Id: 1, Title: "Jan"}, {Id: 2, Title: "Anna"}}, dummyResponses)
+}
+
+func TestClient_GetWithUnsupportedMediaType(t *testing.T) {
+	t.Logf("Given HTTP server responding with text/html")
+	server := httptest.NewServer(contentTypeRequestHandler("text/html", []byte(`<html></html>`)))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling GET")
+	var dummyResponse CodecDummyResponse
+	err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+	t.Logf("Should return UnsupportedMediaTypeError")
+	var mediaTypeError *UnsupportedMediaTypeError
+	assert.True(t, errors.As(err, &mediaTypeError))
+	assert.Equal(t, "text/html", mediaTypeError.ContentType)
+	assert.Equal(t, server.URL, mediaTypeError.Url)
+}
+
+func TestClient_PostWithRequestCodec(t *testing.T) {
+	t.Logf("Given HTTP server echoing request's body and headers")
+	var contentType, accept, body string
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		content, _ := ioutil.ReadAll(req.Body)
+		contentType, accept, body = req.Header.Get("Content-Type"), req.Header.Get("Accept"), string(content)
+		res.Header().Set("Content-Type", "application/json")
+		res.Write([]byte(`{"id":1,"title":"Jan"}`))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		ContentType    string
+		ExpectedBody   string
+		ExpectedAccept string
+	}{
+		{ContentType: "", ExpectedBody: `{"title":"Jan"}`,
+			ExpectedAccept: "application/json, application/xml;q=0.9, application/msgpack;q=0.9, application/x-www-form-urlencoded;q=0.9"},
+		{ContentType: XMLContentType, ExpectedBody: `<DummyRequest><Title>Jan</Title></DummyRequest>`,
+			ExpectedAccept: "application/xml, application/json;q=0.9, application/msgpack;q=0.9, application/x-www-form-urlencoded;q=0.9"},
+		{ContentType: FormContentType, ExpectedBody: `title=Jan`,
+			ExpectedAccept: "application/x-www-form-urlencoded, application/json;q=0.9, application/xml;q=0.9, application/msgpack;q=0.9"},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("And given Client with '%s' content type", testCase.ContentType)
+		client, _ := NewClient(ClientConfig{
+			Retries:     validClientConfig.Retries,
+			Timeout:     validClientConfig.Timeout,
+			ContentType: testCase.ContentType,
+		})
+
+		t.Logf("When calling POST")
+		var dummyResponse DummyResponse
+		err := client.Post(context.Background(), server.URL, &DummyRequest{Title: "Jan"}, &dummyResponse)
+
+		t.Logf("Should encode request with %s codec", testCase.ContentType)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.ExpectedBody, body)
+		assert.Equal(t, testCase.ExpectedAccept, accept)
+		assert.True(t, strings.HasPrefix(testCase.ExpectedAccept, contentType))
+		assert.Equal(t, DummyResponse{Title: "Jan", Id: 1}, dummyResponse)
+	}
+}
+
+func TestClient_GetWithCustomCodec(t *testing.T) {
+	t.Logf("Given HTTP server responding with text/x-title")
+	server := httptest.NewServer(contentTypeRequestHandler("text/x-title", []byte(`Jan`)))
+	defer server.Close()
+
+	t.Logf("And given Client with custom codec")
+	config := validClientConfig
+	config.Codecs = []Codec{titleCodec{}}
+	client, _ := NewClient(config)
+
+	t.Logf("When calling GET")
+	var dummyResponse DummyResponse
+	err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+	t.Logf("Should decode the response with custom codec")
+	assert.NoError(t, err)
+	assert.Equal(t, DummyResponse{Title: "Jan"}, dummyResponse)
+}
+
+func TestFormCodec(t *testing.T) {
+	type Form struct {
+		Id       int      `form:"id"`
+		Title    string   `json:"title"`
+		Tags     []string `form:"tag"`
+		Enabled  bool
+		Skipped  string `form:"-"`
+		Optional string `form:"optional,omitempty"`
+	}
+	form := Form{Id: 1, Title: "Jan", Tags: []string{"a", "b"}, Enabled: true, Skipped: "x"}
+
+	t.Logf("Given form %+v", form)
+	codec := FormCodec{}
+
+	t.Logf("When marshaling and unmarshaling it")
+	marshaled, marshalErr := codec.Marshal(form)
+	var unmarshaled Form
+	unmarshalErr := codec.Unmarshal(marshaled, &unmarshaled)
+	var values url.Values
+	valuesErr := codec.Unmarshal(marshaled, &values)
+
+	t.Logf("Should return the same form without skipped fields")
+	assert.NoError(t, marshalErr)
+	assert.NoError(t, unmarshalErr)
+	assert.NoError(t, valuesErr)
+	assert.Equal(t, "Enabled=true&id=1&tag=a&tag=b&title=Jan", string(marshaled))
+	assert.Equal(t, Form{Id: 1, Title: "Jan", Tags: []string{"a", "b"}, Enabled: true}, unmarshaled)
+	assert.Equal(t, url.Values{"id": {"1"}, "title": {"Jan"}, "tag": {"a", "b"}, "Enabled": {"true"}}, values)
+}
+
+func TestClient_GetWithContentTypeCheck(t *testing.T) {
+	tests := []struct {
+		check       ContentTypeCheck
+		contentType string
+		expectError bool
+	}{
+		{check: LenientContentTypeCheck, contentType: "text/plain", expectError: false},
+		{check: WarnContentTypeCheck, contentType: "text/plain", expectError: false},
+		{check: StrictContentTypeCheck, contentType: "text/plain", expectError: true},
+		{check: StrictContentTypeCheck, contentType: "", expectError: true},
+		{check: StrictContentTypeCheck, contentType: "application/json", expectError: false},
+	}
+	for _, test := range tests {
+		t.Logf("Given HTTP server returning JSON body with Content-Type %q", test.contentType)
+		server := httptest.NewServer(contentTypeRequestHandler(test.contentType, []byte(`{"id":1,"title":"Jan"}`)))
+
+		t.Logf("And given Client with content type check %d", test.check)
+		config := validClientConfig
+		config.ContentTypeCheck = test.check
+		client, _ := NewClient(config)
+
+		t.Logf("When calling GET")
+		var response CodecDummyResponse
+		err := client.Get(context.Background(), server.URL, &response)
+
+		if test.expectError {
+			t.Logf("Should return UnsupportedMediaTypeError")
+			assert.EqualError(t, err, (&UnsupportedMediaTypeError{Url: server.URL, ContentType: test.contentType}).Error())
+		} else {
+			t.Logf("Should decode the body")
+			assert.NoError(t, err)
+			assert.Equal(t, CodecDummyResponse{Id: 1, Title: "Jan"}, response)
+		}
+		server.Close()
+	}
+}
+
+func TestClient_GetWithGenericContentTypeByDefault(t *testing.T) {
+	for _, logging := range []bool{true, false} {
+		t.Logf("Given HTTP server returning JSON body with text/plain Content-Type")
+		server := httptest.NewServer(contentTypeRequestHandler("text/plain", []byte(`{"id":1,"title":"Jan"}`)))
+
+		t.Logf("And given Client with default settings and logging=%t", logging)
+		client, _ := NewClient(ClientConfig{Timeout: time.Second, Retries: validClientConfig.Retries, Logging: logging})
+		var logs bytes.Buffer
+		log.SetOutput(&logs)
+
+		t.Logf("When calling GET")
+		var response CodecDummyResponse
+		err := client.Get(context.Background(), server.URL, &response)
+		log.SetOutput(os.Stderr)
+
+		t.Logf("Should decode the body and log a warning only with logging enabled")
+		assert.NoError(t, err)
+		assert.Equal(t, CodecDummyResponse{Id: 1, Title: "Jan"}, response)
+		assert.Equal(t, logging, strings.Contains(logs.String(), "unexpected Content-Type [text/plain]"))
+		server.Close()
+	}
+}
+
+type titleCodec struct{}
+
+func (titleCodec) ContentType() string {
+	return "text/x-title"
+}
+
+func (titleCodec) Marshal(value interface{}) ([]byte, error) {
+	return []byte(value.(*DummyResponse).Title), nil
+}
+
+func (titleCodec) Unmarshal(data []byte, value interface{}) error {
+	value.(*DummyResponse).Title = string(data)
+	return nil
+}
+
+func contentTypeRequestHandler(contentType string, body []byte) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", contentType)
+		res.Write(body)
+	}
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	corehttp "net/http"
+	"strings"
+)
+
+// Content encodings supported by the Client
+const (
+	GzipEncoding    = "gzip"
+	DeflateEncoding = "deflate"
+	ZstdEncoding    = "zstd"
+)
+
+const defaultMaxDecodedSize = 64 << 20
+
+// Configures compression of request and response bodies.
+type CompressionConfig struct {
+	// Encodings sent in Accept-Encoding header in order of preference (gzip, deflate, zstd),
+	// when empty go's transport asks for gzip on its own
+	AcceptEncodings []string
+	// Request bodies larger than this number of bytes are compressed with gzip, zero disables compression
+	RequestThreshold int
+	// Maximum size of a decoded response body, protects from decompression bombs, defaults to 64MB
+	MaxDecodedSize int64
+}
+
+func validateCompression(config CompressionConfig) error {
+	for _, encoding := range config.AcceptEncodings {
+		if !supportedEncoding(encoding) {
+			return UnsupportedEncodingError
+		}
+	}
+	return nil
+}
+
+func supportedEncoding(encoding string) bool {
+	switch encoding {
+	case GzipEncoding, DeflateEncoding, ZstdEncoding:
+		return true
+	default:
+		return false
+	}
+}
+
+type compression struct {
+	acceptEncoding   string
+	requestThreshold int
+	maxDecodedSize   int64
+}
+
+func newCompression(config CompressionConfig) *compression {
+	maxDecodedSize := config.MaxDecodedSize
+	if maxDecodedSize <= 0 {
+		maxDecodedSize = defaultMaxDecodedSize
+	}
+	return &compression{
+		acceptEncoding:   strings.Join(config.AcceptEncodings, ", "),
+		requestThreshold: config.RequestThreshold,
+		maxDecodedSize:   maxDecodedSize,
+	}
+}
+
+func (c *compression) setHeaders(request *corehttp.Request) {
+	if c.acceptEncoding != "" {
+		request.Header.Set("Accept-Encoding", c.acceptEncoding)
+	}
+}
+
+// Compresses the body with gzip if it is larger than the threshold, returns whether it was compressed
+func (c *compression) compress(body []byte) ([]byte, bool, error) {
+	if c.requestThreshold <= 0 || len(body) <= c.requestThreshold {
+		return body, false, nil
+	}
+
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(body); err != nil {
+		return nil, false, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, false, err
+	}
+	return buffer.Bytes(), true, nil
+}
+
+// Reads the decoded body, returns DecodedSizeExceededError once it exceeds the maximum size
+func (c *compression) readAll(reader io.Reader) ([]byte, error) {
+	var buffer bytes.Buffer
+	if _, err := io.Copy(&buffer, io.LimitReader(reader, c.maxDecodedSize+1)); err != nil {
+		return nil, err
+	}
+	if int64(buffer.Len()) > c.maxDecodedSize {
+		return nil, DecodedSizeExceededError
+	}
+	return buffer.Bytes(), nil
+}
+
+// Wraps the response body with decoders according to its Content-Encoding header,
+// returned closers have to be closed once the body is read
+func (c *compression) decoder(response *corehttp.Response) (io.Reader, []io.Closer, error) {
+	var reader io.Reader = response.Body
+	var closers []io.Closer
+	if response.Uncompressed {
+		return reader, closers, nil
+	}
+
+	encodings := strings.Split(response.Header.Get("Content-Encoding"), ",")
+	// Encodings are listed in the order they were applied
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch strings.ToLower(strings.TrimSpace(encodings[i])) {
+		case "", "identity":
+		case GzipEncoding, "x-gzip":
+			gzipReader, err := gzip.NewReader(reader)
+			if err == io.EOF {
+				return bytes.NewReader(nil), closers, nil
+			}
+			if err != nil {
+				return nil, closers, err
+			}
+			closers = append(closers, gzipReader)
+			reader = gzipReader
+		case DeflateEncoding:
+			zlibReader, err := zlib.NewReader(reader)
+			if err == io.EOF {
+				return bytes.NewReader(nil), closers, nil
+			}
+			if err != nil {
+				return nil, closers, err
+			}
+			closers = append(closers, zlibReader)
+			reader = zlibReader
+		case ZstdEncoding:
+			decoder, err := zstd.NewReader(reader, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(c.maxDecodedSize)))
+			if err != nil {
+				return nil, closers, err
+			}
+			zstdReader := decoder.IOReadCloser()
+			closers = append(closers, zstdReader)
+			reader = zstdReader
+		default:
+			return nil, closers, UnsupportedEncodingError
+		}
+	}
+	return reader, closers, nil
+}
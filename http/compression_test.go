@@ -0,0 +1,138 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewClientWithUnsupportedEncoding(t *testing.T) {
+	t.Logf("Given ClientConfig with brotli encoding")
+	config := validClientConfig
+	config.Compression = CompressionConfig{AcceptEncodings: []string{GzipEncoding, "br"}}
+
+	t.Logf("When creating Client")
+	client, err := NewClient(config)
+
+	t.Logf("Should return UnsupportedEncodingError")
+	assert.Equal(t, UnsupportedEncodingError, err)
+	assert.Nil(t, client)
+}
+
+func TestClient_GetWithCompressedResponse(t *testing.T) {
+	t.Logf("Given HTTP server compressing responses with requested encoding")
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		acceptEncoding = req.Header.Get("Accept-Encoding")
+		encoding := strings.Split(acceptEncoding, ",")[0]
+		res.Header().Set("Content-Encoding", encoding)
+		res.Write(compress(t, encoding, []byte(`{"id":1,"title":"Jan"}`)))
+	}))
+	defer server.Close()
+
+	for _, encoding := range []string{GzipEncoding, DeflateEncoding, ZstdEncoding} {
+		t.Logf("And given Client accepting %s encoding", encoding)
+		config := validClientConfig
+		config.Compression = CompressionConfig{AcceptEncodings: []string{encoding, GzipEncoding}}
+		client, _ := NewClient(config)
+
+		t.Logf("When calling GET")
+		var dummyResponse DummyResponse
+		err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+		t.Logf("Should return decoded DummyResponse")
+		assert.NoError(t, err)
+		assert.Equal(t, encoding+", gzip", acceptEncoding)
+		assert.Equal(t, DummyResponse{Title: "Jan", Id: 1}, dummyResponse)
+	}
+}
+
+func TestClient_GetWithDecompressionBomb(t *testing.T) {
+	t.Logf("Given HTTP server returning 1MB of gzipped zeros")
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Encoding", GzipEncoding)
+		res.Write(compress(t, GzipEncoding, make([]byte, 1<<20)))
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client with maximum decoded size of 1KB")
+	config := validClientConfig
+	config.Compression = CompressionConfig{AcceptEncodings: []string{GzipEncoding}, MaxDecodedSize: 1 << 10}
+	client, _ := NewClient(config)
+
+	t.Logf("When calling GET")
+	var dummyResponse DummyResponse
+	err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+	t.Logf("Should return ClientError with decoding message")
+	var clientError *ClientError
+	assert.True(t, errors.As(err, &clientError))
+	assert.Equal(t, "decoding error", clientError.Message)
+	assert.True(t, errors.Is(err, DecodedSizeExceededError))
+}
+
+func TestClient_PostWithCompressedRequest(t *testing.T) {
+	t.Logf("Given HTTP server echoing request's title and encoding")
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		var body io.Reader = req.Body
+		if req.Header.Get("Content-Encoding") == GzipEncoding {
+			body, _ = gzip.NewReader(req.Body)
+		}
+		content, _ := ioutil.ReadAll(body)
+		res.Write([]byte(`{"id":1,"title":"` + req.Header.Get("Content-Encoding") + `"}`))
+		assert.Equal(t, `{"title":"Jan Kowalski"}`, string(content))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		Threshold        int
+		ExpectedEncoding string
+	}{
+		{Threshold: 10, ExpectedEncoding: GzipEncoding},
+		{Threshold: 1024, ExpectedEncoding: ""},
+		{Threshold: 0, ExpectedEncoding: ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("And given Client compressing requests larger than %d bytes", testCase.Threshold)
+		config := validClientConfig
+		config.Compression = CompressionConfig{RequestThreshold: testCase.Threshold}
+		client, _ := NewClient(config)
+
+		t.Logf("When calling POST")
+		var dummyResponse DummyResponse
+		err := client.Post(context.Background(), server.URL, &DummyRequest{Title: "Jan Kowalski"}, &dummyResponse)
+
+		t.Logf("Should send request with '%s' encoding", testCase.ExpectedEncoding)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.ExpectedEncoding, dummyResponse.Title)
+	}
+}
+
+func compress(t *testing.T, encoding string, content []byte) []byte {
+	var buffer bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case GzipEncoding:
+		writer = gzip.NewWriter(&buffer)
+	case DeflateEncoding:
+		writer = zlib.NewWriter(&buffer)
+	case ZstdEncoding:
+		writer, _ = zstd.NewWriter(&buffer)
+	default:
+		t.Fatalf("unknown encoding %s", encoding)
+	}
+	writer.Write(content)
+	writer.Close()
+	return buffer.Bytes()
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSRVTTL     = 30 * time.Second
+	defaultSRVTimeout = 5 * time.Second
+)
+
+// Looks up DNS SRV records, satisfied by *net.Resolver
+type SRVResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// Configures discovery of endpoints from DNS SRV records, e.g. _inventory._tcp.example.com.
+//
+// Priorities and weights of the records become priorities and weights of the endpoints, records are cached
+// for TTL and resolved again afterwards. When the resolution fails the last known records are kept.
+// Discovery is disabled when Name is empty.
+type SRVConfig struct {
+	// Service and Proto are optional, when both are empty Name is looked up directly
+	Service string
+	Proto   string
+	Name    string
+	// Scheme of the endpoints, defaults to https
+	Scheme string
+	// Optional path prefix of the endpoints, e.g. /api
+	Path string
+	// Defaults to 30s
+	TTL time.Duration
+	// Timeout of a single resolution, defaults to 5s
+	Timeout time.Duration
+	// Defaults to net.DefaultResolver
+	Resolver SRVResolver
+}
+
+func (c SRVConfig) enabled() bool {
+	return c.Name != ""
+}
+
+type discovery struct {
+	config  SRVConfig
+	ttl     time.Duration
+	timeout time.Duration
+}
+
+func newDiscovery(config SRVConfig) *discovery {
+	discovery := &discovery{config: config, ttl: config.TTL, timeout: config.Timeout}
+	if discovery.ttl <= 0 {
+		discovery.ttl = defaultSRVTTL
+	}
+	if discovery.timeout <= 0 {
+		discovery.timeout = defaultSRVTimeout
+	}
+	if discovery.config.Resolver == nil {
+		discovery.config.Resolver = net.DefaultResolver
+	}
+	if discovery.config.Scheme == "" {
+		discovery.config.Scheme = "https"
+	}
+	return discovery
+}
+
+// Resolves the records into endpoints, errors (including no records) are wrapped by DiscoveryError
+func (d *discovery) lookup() ([]Endpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	_, records, err := d.config.Resolver.LookupSRV(ctx, d.config.Service, d.config.Proto, d.config.Name)
+	if err != nil {
+		return nil, &DiscoveryError{Name: d.config.Name, Err: err}
+	}
+	if len(records) == 0 {
+		return nil, &DiscoveryError{Name: d.config.Name, Err: NoSRVRecordsError}
+	}
+
+	var endpoints []Endpoint
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		endpoints = append(endpoints, Endpoint{
+			URL: url.URL{
+				Scheme: d.config.Scheme,
+				Host:   net.JoinHostPort(host, strconv.Itoa(int(record.Port))),
+				Path:   d.config.Path,
+			},
+			Weight:   int(record.Weight),
+			Priority: int(record.Priority),
+		})
+	}
+	return endpoints, nil
+}
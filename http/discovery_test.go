@@ -0,0 +1,247 @@
+package http
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const fakeSRVName = "_inventory._tcp.example.test."
+
+func TestClient_GetWithSRVDiscovery(t *testing.T) {
+	t.Logf("Given two HTTP servers returning 200 status")
+	var firstCount, secondCount int32
+	first := httptest.NewServer(endpointRequestHandler(200, 0, &firstCount))
+	defer first.Close()
+	second := httptest.NewServer(endpointRequestHandler(200, 0, &secondCount))
+	defer second.Close()
+
+	t.Logf("And given DNS server with SRV records of both of them with weights 3 and 1")
+	dns := newFakeDNS(t)
+	defer dns.close()
+	dns.setRecords(fakeSRVName, srvRecord(t, first, 0, 3), srvRecord(t, second, 0, 1))
+
+	t.Logf("And given Client discovering the endpoints with weighted balancing")
+	client := newEndpointsClient(t, EndpointsConfig{
+		Balancing: WeightedBalancing,
+		SRV:       SRVConfig{Service: "inventory", Proto: "tcp", Name: "example.test.", Scheme: "http", Resolver: dns.resolver()},
+	})
+	defer client.Close()
+
+	t.Logf("When calling GET for a relative url 8 times")
+	for i := 0; i < 8; i++ {
+		assert.NoError(t, client.Get(context.Background(), "/dummy", &DummyResponse{}))
+	}
+
+	t.Logf("Should send 6 requests to the first server and 2 to the second one")
+	assert.Equal(t, int32(6), atomic.LoadInt32(&firstCount))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&secondCount))
+}
+
+func TestClient_GetWithSRVPriorities(t *testing.T) {
+	t.Logf("Given HTTP server returning 500 status and another one returning 200 status")
+	var primaryCount, backupCount int32
+	primary := httptest.NewServer(endpointRequestHandler(500, 0, &primaryCount))
+	defer primary.Close()
+	backup := httptest.NewServer(endpointRequestHandler(200, 0, &backupCount))
+	defer backup.Close()
+
+	t.Logf("And given DNS server with SRV records of the failing one with priority 0 and the other with priority 1")
+	dns := newFakeDNS(t)
+	defer dns.close()
+	dns.setRecords(fakeSRVName, srvRecord(t, primary, 0, 1), srvRecord(t, backup, 1, 1))
+
+	t.Logf("And given Client ejecting endpoints after 2 failures")
+	client := newEndpointsClient(t, EndpointsConfig{
+		MaxFailures: 2,
+		SRV:         SRVConfig{Service: "inventory", Proto: "tcp", Name: "example.test.", Scheme: "http", Resolver: dns.resolver()},
+	})
+	defer client.Close()
+
+	t.Logf("When calling GET for a relative url")
+	err := client.Get(context.Background(), "/dummy", &DummyResponse{})
+
+	t.Logf("Should use the backup only once the primary is ejected")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&primaryCount))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&backupCount))
+}
+
+func TestClient_GetWithSRVRefresh(t *testing.T) {
+	t.Logf("Given two HTTP servers returning 200 status")
+	var firstCount, secondCount int32
+	first := httptest.NewServer(endpointRequestHandler(200, 0, &firstCount))
+	defer first.Close()
+	second := httptest.NewServer(endpointRequestHandler(200, 0, &secondCount))
+	defer second.Close()
+
+	t.Logf("And given DNS server with SRV record of the first one")
+	dns := newFakeDNS(t)
+	defer dns.close()
+	dns.setRecords(fakeSRVName, srvRecord(t, first, 0, 1))
+
+	t.Logf("And given Client discovering the endpoints every 10ms")
+	client := newEndpointsClient(t, EndpointsConfig{
+		SRV: SRVConfig{Service: "inventory", Proto: "tcp", Name: "example.test.", Scheme: "http", TTL: 10 * time.Millisecond, Resolver: dns.resolver()},
+	})
+	defer client.Close()
+	assert.NoError(t, client.Get(context.Background(), "/dummy", &DummyResponse{}))
+
+	t.Logf("When the record changes to the second server and then DNS server starts failing")
+	dns.setRecords(fakeSRVName, srvRecord(t, second, 0, 1))
+	waitFor(t, func() bool { return dns.queries() >= 3 })
+	dns.setRecords(fakeSRVName)
+	waitFor(t, func() bool { return dns.queries() >= 5 })
+	assert.NoError(t, client.Get(context.Background(), "/dummy", &DummyResponse{}))
+
+	t.Logf("Should send the next request to the last known server")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&firstCount))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&secondCount))
+}
+
+func TestNewClientWithoutSRVRecords(t *testing.T) {
+	t.Logf("Given DNS server without SRV records")
+	dns := newFakeDNS(t)
+	defer dns.close()
+
+	t.Logf("When creating Client discovering the endpoints")
+	config := validClientConfig
+	config.Endpoints = EndpointsConfig{
+		SRV: SRVConfig{Service: "inventory", Proto: "tcp", Name: "example.test.", Resolver: dns.resolver()},
+	}
+	client, err := NewClient(config)
+
+	t.Logf("Should return DiscoveryError")
+	assert.Nil(t, client)
+	var discoveryError *DiscoveryError
+	assert.True(t, errors.As(err, &discoveryError))
+	assert.Equal(t, "example.test.", discoveryError.Name)
+}
+
+func srvRecord(t *testing.T, server *httptest.Server, priority uint16, weight uint16) *net.SRV {
+	serverUrl, _ := url.Parse(server.URL)
+	port, err := strconv.Atoi(serverUrl.Port())
+	assert.NoError(t, err)
+	// Addresses are not valid targets of SRV records, httptest servers listen on the loopback interface
+	return &net.SRV{Target: "localhost.", Port: uint16(port), Priority: priority, Weight: weight}
+}
+
+// Minimal DNS server answering SRV queries over UDP, names without records are answered with NXDOMAIN
+type fakeDNS struct {
+	conn    net.PacketConn
+	mutex   sync.Mutex
+	records map[string][]*net.SRV
+	count   int32
+}
+
+func newFakeDNS(t *testing.T) *fakeDNS {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	dns := &fakeDNS{conn: conn, records: map[string][]*net.SRV{}}
+	go dns.serve()
+	return dns
+}
+
+func (d *fakeDNS) setRecords(name string, records ...*net.SRV) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.records[name] = records
+}
+
+func (d *fakeDNS) queries() int32 {
+	return atomic.LoadInt32(&d.count)
+}
+
+func (d *fakeDNS) resolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "udp", d.conn.LocalAddr().String())
+		},
+	}
+}
+
+func (d *fakeDNS) close() {
+	d.conn.Close()
+}
+
+func (d *fakeDNS) serve() {
+	buffer := make([]byte, 512)
+	for {
+		n, address, err := d.conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		if response := d.answer(buffer[:n]); response != nil {
+			d.conn.WriteTo(response, address)
+		}
+	}
+}
+
+func (d *fakeDNS) answer(query []byte) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+	atomic.AddInt32(&d.count, 1)
+
+	// Question starts right after the header, it's a sequence of labels followed by type and class
+	end := 12
+	var labels []string
+	for end < len(query) && query[end] != 0 {
+		length := int(query[end])
+		labels = append(labels, string(query[end+1:end+1+length]))
+		end += length + 1
+	}
+	end += 5
+	name := strings.ToLower(strings.Join(labels, ".")) + "."
+
+	d.mutex.Lock()
+	records := d.records[name]
+	d.mutex.Unlock()
+
+	response := make([]byte, 12)
+	copy(response, query[:2])
+	flags := uint16(0x8180)
+	if len(records) == 0 {
+		flags |= 3
+	}
+	binary.BigEndian.PutUint16(response[2:], flags)
+	binary.BigEndian.PutUint16(response[4:], 1)
+	binary.BigEndian.PutUint16(response[6:], uint16(len(records)))
+	response = append(response, query[12:end]...)
+	for _, record := range records {
+		target := encodeName(record.Target)
+		// Pointer to the name of the question, SRV type, IN class and TTL of 1s
+		response = append(response, 0xc0, 0x0c, 0, 33, 0, 1, 0, 0, 0, 1)
+		response = appendUint16(response, uint16(6+len(target)))
+		response = appendUint16(response, record.Priority)
+		response = appendUint16(response, record.Weight)
+		response = appendUint16(response, record.Port)
+		response = append(response, target...)
+	}
+	return response
+}
+
+func encodeName(name string) []byte {
+	var encoded []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		encoded = append(encoded, byte(len(label)))
+		encoded = append(encoded, label...)
+	}
+	return append(encoded, 0)
+}
+
+func appendUint16(data []byte, value uint16) []byte {
+	return append(data, byte(value>>8), byte(value))
+}
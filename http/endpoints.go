@@ -0,0 +1,409 @@
+package http
+
+import (
+	"context"
+	"log"
+	corehttp "net/http"
+	"net/url"
+	"sync"
+	"test2/clock"
+	"time"
+)
+
+const (
+	defaultMaxFailures         = 3
+	defaultEjectionTime        = 30 * time.Second
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 2 * time.Second
+	// Weight of the newest sample in the moving average of endpoint latencies
+	latencyDecay = 0.3
+)
+
+// Strategy of picking an endpoint for every attempt
+type Balancing int
+
+const (
+	RoundRobinBalancing Balancing = iota
+	// Smooth weighted round robin, Endpoint.Weight defaults to 1
+	WeightedBalancing
+	// Picks the endpoint with the lowest moving average of latencies, not yet measured endpoints go first
+	LeastLatencyBalancing
+)
+
+// Base url of one of the replicas of a service, e.g. https://eu.example.com/api
+type Endpoint struct {
+	URL    url.URL
+	Weight int
+	// Endpoints with lower priority are preferred, the higher ones are used only when all of the lower ones
+	// are ejected or unhealthy
+	Priority int
+}
+
+// Configures client-side load balancing between several endpoints.
+//
+// Relative urls passed to the Client (e.g. /inventory/7) are resolved against the endpoint picked for every attempt,
+// so retries move to a different endpoint whenever there's one available. Absolute urls are sent as they are.
+type EndpointsConfig struct {
+	Endpoints []Endpoint
+	Balancing Balancing
+	// Number of consecutive failures (network errors, >=500 statuses) after which an endpoint is ejected, defaults to 3
+	MaxFailures int
+	// How long an ejected endpoint is skipped, defaults to 30s
+	EjectionTime time.Duration
+	// Optional active health checking of the endpoints
+	HealthCheck HealthCheckConfig
+	// Optional discovery of additional endpoints from DNS SRV records
+	SRV SRVConfig
+}
+
+// Configures periodic GET requests against every endpoint, endpoints answering with anything else than 2xx
+// are skipped until they pass the check again. It is disabled when Path is empty.
+type HealthCheckConfig struct {
+	// Path relative to the endpoint's url, e.g. /health
+	Path string
+	// Defaults to 10s
+	Interval time.Duration
+	// Defaults to 2s
+	Timeout time.Duration
+}
+
+func (c EndpointsConfig) enabled() bool {
+	return len(c.Endpoints) > 0 || c.SRV.enabled()
+}
+
+func validateEndpoints(config EndpointsConfig) error {
+	for _, endpoint := range config.Endpoints {
+		if !endpoint.URL.IsAbs() || endpoint.URL.Host == "" || endpoint.Weight < 0 {
+			return InvalidEndpointError
+		}
+	}
+	return nil
+}
+
+func newEndpoint(config Endpoint) *endpoint {
+	weight := config.Weight
+	if weight == 0 {
+		weight = 1
+	}
+	return &endpoint{url: config.URL, weight: weight, priority: config.Priority}
+}
+
+type endpoint struct {
+	url          url.URL
+	weight       int
+	priority     int
+	current      int
+	failures     int
+	ejectedUntil time.Time
+	unhealthy    bool
+	latency      time.Duration
+	measured     bool
+}
+
+type endpoints struct {
+	mutex        sync.Mutex
+	endpoints    []*endpoint
+	static       []*endpoint
+	discovery    *discovery
+	balancing    Balancing
+	maxFailures  int
+	ejectionTime time.Duration
+	next         int
+	metrics      *metrics
+	logging      bool
+
+	healthCheck HealthCheckConfig
+	client      *corehttp.Client
+	clock       clock.Clock
+	stop        chan struct{}
+	stopped     sync.WaitGroup
+	// Number of Clients sharing the pool, it's closed once all of them are closed
+	refs int
+}
+
+// Creates the pool and resolves its SRV records, any error of the initial resolution is returned to the caller
+func newEndpoints(config EndpointsConfig, client *corehttp.Client, metrics *metrics, clock clock.Clock, logging bool) (*endpoints, error) {
+	if !config.enabled() {
+		return nil, nil
+	}
+	pool := &endpoints{
+		balancing:    config.Balancing,
+		maxFailures:  config.MaxFailures,
+		ejectionTime: config.EjectionTime,
+		metrics:      metrics,
+		logging:      logging,
+		healthCheck:  config.HealthCheck,
+		client:       client,
+		clock:        clock,
+		stop:         make(chan struct{}),
+		refs:         1,
+	}
+	if pool.maxFailures <= 0 {
+		pool.maxFailures = defaultMaxFailures
+	}
+	if pool.ejectionTime <= 0 {
+		pool.ejectionTime = defaultEjectionTime
+	}
+	if pool.healthCheck.Interval <= 0 {
+		pool.healthCheck.Interval = defaultHealthCheckInterval
+	}
+	if pool.healthCheck.Timeout <= 0 {
+		pool.healthCheck.Timeout = defaultHealthCheckTimeout
+	}
+	for _, config := range config.Endpoints {
+		pool.static = append(pool.static, newEndpoint(config))
+	}
+	pool.endpoints = pool.static
+
+	if config.SRV.enabled() {
+		pool.discovery = newDiscovery(config.SRV)
+		discovered, err := pool.discovery.lookup()
+		if err != nil {
+			return nil, err
+		}
+		pool.update(discovered)
+		pool.stopped.Add(1)
+		go pool.runDiscovery()
+	}
+	if pool.healthCheck.Path != "" {
+		pool.stopped.Add(1)
+		go pool.runHealthChecks()
+	}
+	return pool, nil
+}
+
+// Replaces discovered endpoints, the ones which are still present keep their failures and latencies
+func (p *endpoints) update(discovered []Endpoint) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	existing := map[string]*endpoint{}
+	for _, endpoint := range p.endpoints {
+		existing[endpoint.url.String()] = endpoint
+	}
+	updated := append([]*endpoint{}, p.static...)
+	for _, config := range discovered {
+		fresh := newEndpoint(config)
+		if current, ok := existing[config.URL.String()]; ok {
+			current.weight = fresh.weight
+			current.priority = fresh.priority
+			fresh = current
+		}
+		updated = append(updated, fresh)
+	}
+	p.endpoints = updated
+}
+
+func (p *endpoints) runDiscovery() {
+	defer p.stopped.Done()
+	timer := p.clock.NewTimer(p.discovery.ttl)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C():
+		case <-p.stop:
+			return
+		}
+		discovered, err := p.discovery.lookup()
+		if err != nil {
+			// Last known records are kept until the next resolution succeeds
+			if p.logging {
+				log.Printf("Resolution of SRV records failed: %s \n", err)
+			}
+		} else {
+			p.update(discovered)
+		}
+		timer.Reset(p.discovery.ttl)
+	}
+}
+
+// Resolves the relative url of the attempt against the picked endpoint, previous is the endpoint of the previous
+// attempt which is avoided when possible. Returns nil endpoint for absolute urls
+func (p *endpoints) resolve(attempt *corehttp.Request, previous *endpoint) (*endpoint, error) {
+	if attempt.URL.IsAbs() {
+		return nil, nil
+	}
+	picked := p.pick(previous)
+	resolved, err := joinURL(picked.url, attempt.URL.EscapedPath(), attempt.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+	attempt.URL = resolved
+	attempt.Host = ""
+	return picked, nil
+}
+
+func (p *endpoints) pick(previous *endpoint) *endpoint {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	candidates := p.available(p.clock.Now())
+	if len(candidates) > 1 && previous != nil {
+		for i, candidate := range candidates {
+			if candidate == previous {
+				candidates = append(candidates[:i:i], candidates[i+1:]...)
+				break
+			}
+		}
+	}
+
+	switch p.balancing {
+	case WeightedBalancing:
+		return pickWeighted(candidates)
+	case LeastLatencyBalancing:
+		return pickLeastLatency(candidates)
+	default:
+		picked := candidates[p.next%len(candidates)]
+		p.next++
+		return picked
+	}
+}
+
+// Endpoints with the lowest priority which are neither ejected nor unhealthy, all of them when none is available
+func (p *endpoints) available(now time.Time) []*endpoint {
+	var available []*endpoint
+	for _, endpoint := range p.endpoints {
+		if !endpoint.unhealthy && !now.Before(endpoint.ejectedUntil) {
+			available = append(available, endpoint)
+		}
+	}
+	if len(available) == 0 {
+		available = p.endpoints
+	}
+
+	var preferred []*endpoint
+	for _, endpoint := range available {
+		if len(preferred) > 0 && endpoint.priority > preferred[0].priority {
+			continue
+		}
+		if len(preferred) > 0 && endpoint.priority < preferred[0].priority {
+			preferred = preferred[:0]
+		}
+		preferred = append(preferred, endpoint)
+	}
+	return preferred
+}
+
+func pickWeighted(candidates []*endpoint) *endpoint {
+	total := 0
+	var picked *endpoint
+	for _, candidate := range candidates {
+		candidate.current += candidate.weight
+		total += candidate.weight
+		if picked == nil || candidate.current > picked.current {
+			picked = candidate
+		}
+	}
+	picked.current -= total
+	return picked
+}
+
+func pickLeastLatency(candidates []*endpoint) *endpoint {
+	picked := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if !candidate.measured && picked.measured || candidate.measured == picked.measured && candidate.latency < picked.latency {
+			picked = candidate
+		}
+	}
+	return picked
+}
+
+// Records the outcome of an attempt, the endpoint is ejected after MaxFailures consecutive failures
+func (p *endpoints) report(picked *endpoint, elapsed time.Duration, response *corehttp.Response, err error) {
+	if picked == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !shouldRetry(response, err) {
+		picked.failures = 0
+		if picked.measured {
+			picked.latency = time.Duration(latencyDecay*float64(elapsed) + (1-latencyDecay)*float64(picked.latency))
+		} else {
+			picked.latency = elapsed
+			picked.measured = true
+		}
+		return
+	}
+
+	picked.failures++
+	if picked.failures >= p.maxFailures {
+		picked.failures = 0
+		picked.ejectedUntil = p.clock.Now().Add(p.ejectionTime)
+		p.metrics.eject()
+		if p.logging {
+			log.Printf("Endpoint [%s] ejected for [%s] \n", picked.url.String(), p.ejectionTime.String())
+		}
+	}
+}
+
+func (p *endpoints) runHealthChecks() {
+	defer p.stopped.Done()
+	timer := p.clock.NewTimer(p.healthCheck.Interval)
+	defer timer.Stop()
+	for {
+		p.checkAll()
+		select {
+		case <-timer.C():
+		case <-p.stop:
+			return
+		}
+		timer.Reset(p.healthCheck.Interval)
+	}
+}
+
+func (p *endpoints) checkAll() {
+	p.mutex.Lock()
+	checked := p.endpoints
+	p.mutex.Unlock()
+	for _, endpoint := range checked {
+		healthy := p.check(endpoint)
+		p.mutex.Lock()
+		endpoint.unhealthy = !healthy
+		if healthy {
+			endpoint.ejectedUntil = time.Time{}
+		}
+		p.mutex.Unlock()
+	}
+}
+
+func (p *endpoints) check(endpoint *endpoint) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), p.healthCheck.Timeout)
+	defer cancel()
+
+	checkUrl, err := joinURL(endpoint.url, p.healthCheck.Path, nil)
+	if err != nil {
+		return false
+	}
+	request, err := corehttp.NewRequestWithContext(ctx, corehttp.MethodGet, checkUrl.String(), nil)
+	if err != nil {
+		return false
+	}
+	response, err := p.client.Do(request)
+	if err != nil {
+		return false
+	}
+	closeResponse(response)
+	return response.StatusCode >= 200 && response.StatusCode < 300
+}
+
+// Shares the pool with another Client
+func (p *endpoints) retain() *endpoints {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.refs++
+	return p
+}
+
+// Stops health checks and discovery once the last Client sharing the pool releases it
+func (p *endpoints) release() {
+	p.mutex.Lock()
+	p.refs--
+	last := p.refs == 0
+	p.mutex.Unlock()
+	if last {
+		close(p.stop)
+		p.stopped.Wait()
+	}
+}
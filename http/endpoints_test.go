@@ -0,0 +1,318 @@
+package http
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"test2/clock"
+	"testing"
+	"time"
+)
+
+func TestNewClientWithInvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []Endpoint{
+		{URL: url.URL{Path: "/api"}},
+		{URL: url.URL{Scheme: "http", Host: "localhost"}, Weight: -1},
+	} {
+		t.Logf("Given ClientConfig with endpoint %+v", endpoint)
+		config := validClientConfig
+		config.Endpoints = EndpointsConfig{Endpoints: []Endpoint{endpoint}}
+
+		t.Logf("When creating Client")
+		client, err := NewClient(config)
+
+		t.Logf("Should return InvalidEndpointError")
+		assert.Nil(t, client)
+		assert.Equal(t, InvalidEndpointError, err)
+	}
+}
+
+func TestClient_GetWithRoundRobinBalancing(t *testing.T) {
+	t.Logf("Given two HTTP servers returning 200 status")
+	var firstCount, secondCount int32
+	first := httptest.NewServer(endpointRequestHandler(200, 0, &firstCount))
+	defer first.Close()
+	second := httptest.NewServer(endpointRequestHandler(200, 0, &secondCount))
+	defer second.Close()
+
+	t.Logf("And given Client balancing between them with round robin")
+	client := newEndpointsClient(t, EndpointsConfig{Endpoints: []Endpoint{serverEndpoint(first, 0), serverEndpoint(second, 0)}})
+	defer client.Close()
+
+	t.Logf("When calling GET for a relative url 4 times")
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, client.Get(context.Background(), "/dummy", &DummyResponse{}))
+	}
+
+	t.Logf("Should send 2 requests to each of the servers")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&firstCount))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&secondCount))
+}
+
+func TestClient_GetWithWeightedBalancing(t *testing.T) {
+	t.Logf("Given two HTTP servers returning 200 status")
+	var firstCount, secondCount int32
+	first := httptest.NewServer(endpointRequestHandler(200, 0, &firstCount))
+	defer first.Close()
+	second := httptest.NewServer(endpointRequestHandler(200, 0, &secondCount))
+	defer second.Close()
+
+	t.Logf("And given Client balancing between them with weights 3 and 1")
+	client := newEndpointsClient(t, EndpointsConfig{
+		Endpoints: []Endpoint{serverEndpoint(first, 3), serverEndpoint(second, 1)},
+		Balancing: WeightedBalancing,
+	})
+	defer client.Close()
+
+	t.Logf("When calling GET for a relative url 8 times")
+	for i := 0; i < 8; i++ {
+		assert.NoError(t, client.Get(context.Background(), "/dummy", &DummyResponse{}))
+	}
+
+	t.Logf("Should send 6 requests to the first server and 2 to the second one")
+	assert.Equal(t, int32(6), atomic.LoadInt32(&firstCount))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&secondCount))
+}
+
+func TestClient_GetWithLeastLatencyBalancing(t *testing.T) {
+	t.Logf("Given slow and fast HTTP servers")
+	var slowCount, fastCount int32
+	slow := httptest.NewServer(endpointRequestHandler(200, 50*time.Millisecond, &slowCount))
+	defer slow.Close()
+	fast := httptest.NewServer(endpointRequestHandler(200, 0, &fastCount))
+	defer fast.Close()
+
+	t.Logf("And given Client balancing between them by latency")
+	client := newEndpointsClient(t, EndpointsConfig{
+		Endpoints: []Endpoint{serverEndpoint(slow, 0), serverEndpoint(fast, 0)},
+		Balancing: LeastLatencyBalancing,
+	})
+	defer client.Close()
+
+	t.Logf("When calling GET for a relative url 6 times")
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, client.Get(context.Background(), "/dummy", &DummyResponse{}))
+	}
+
+	t.Logf("Should measure the slow server once and then stick to the fast one")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&slowCount))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&fastCount))
+}
+
+func TestClient_GetWithFailingEndpoint(t *testing.T) {
+	t.Logf("Given HTTP server returning 500 status and another one returning 200 status")
+	var failingCount, healthyCount int32
+	failing := httptest.NewServer(endpointRequestHandler(500, 0, &failingCount))
+	defer failing.Close()
+	healthy := httptest.NewServer(endpointRequestHandler(200, 0, &healthyCount))
+	defer healthy.Close()
+
+	t.Logf("And given Client ejecting endpoints after 2 failures")
+	client := newEndpointsClient(t, EndpointsConfig{
+		Endpoints:   []Endpoint{serverEndpoint(failing, 0), serverEndpoint(healthy, 0)},
+		MaxFailures: 2,
+	})
+	defer client.Close()
+
+	t.Logf("When calling GET for a relative url 6 times")
+	var responses []*Response
+	for i := 0; i < 6; i++ {
+		response, err := client.Do(context.Background(), "GET", "/dummy", nil, &DummyResponse{})
+		assert.NoError(t, err)
+		responses = append(responses, response)
+	}
+
+	t.Logf("Should retry on the healthy server and stop calling the failing one once it's ejected")
+	assert.Equal(t, 2, responses[0].Attempts)
+	assert.Equal(t, healthy.URL+"/dummy", responses[0].URL.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&failingCount))
+	assert.Equal(t, int32(6), atomic.LoadInt32(&healthyCount))
+	assert.Equal(t, int64(1), client.Metrics().Ejections)
+}
+
+func TestClient_GetWithEjectionExpiredOnClock(t *testing.T) {
+	t.Logf("Given HTTP server returning 500 status and another one returning 200 status")
+	var failingCount, healthyCount int32
+	failing := httptest.NewServer(endpointRequestHandler(500, 0, &failingCount))
+	defer failing.Close()
+	healthy := httptest.NewServer(endpointRequestHandler(200, 0, &healthyCount))
+	defer healthy.Close()
+
+	t.Logf("And given Client on manual clock ejecting endpoints for 1m after a single failure")
+	manual := clock.NewManual(time.Now())
+	config := validClientConfig
+	config.Clock = manual
+	config.Retries.Clock = clock.System
+	config.Endpoints = EndpointsConfig{
+		Endpoints:    []Endpoint{serverEndpoint(failing, 0), serverEndpoint(healthy, 0)},
+		MaxFailures:  1,
+		EjectionTime: time.Minute,
+	}
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	t.Logf("When calling GET for a relative url 3 times")
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, client.Get(context.Background(), "/dummy", &DummyResponse{}))
+	}
+
+	t.Logf("Should call the failing server only before it's ejected")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&failingCount))
+
+	t.Logf("When advancing the clock past the ejection time and calling GET 2 more times")
+	manual.Advance(time.Minute)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, client.Get(context.Background(), "/dummy", &DummyResponse{}))
+	}
+
+	t.Logf("Should call the failing server again")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&failingCount))
+	assert.Equal(t, int64(2), client.Metrics().Ejections)
+}
+
+func TestClient_GetWithHealthCheck(t *testing.T) {
+	t.Logf("Given HTTP server failing its health check and another one passing it")
+	var unhealthyCount, healthyCount int32
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/api/health" {
+			res.WriteHeader(503)
+			return
+		}
+		endpointRequestHandler(200, 0, &unhealthyCount)(res, req)
+	}))
+	defer unhealthy.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/health" {
+			return
+		}
+		endpointRequestHandler(200, 0, &healthyCount)(res, req)
+	}))
+	defer healthy.Close()
+
+	t.Logf("And given Client checking /health of both of them")
+	unhealthyEndpoint, _ := url.Parse(unhealthy.URL + "/api")
+	client := newEndpointsClient(t, EndpointsConfig{
+		Endpoints:   []Endpoint{{URL: *unhealthyEndpoint}, serverEndpoint(healthy, 0)},
+		HealthCheck: HealthCheckConfig{Path: "/health", Interval: 10 * time.Millisecond},
+	})
+	defer client.Close()
+
+	t.Logf("When calling GET for a relative url 4 times after the first health check")
+	waitFor(t, func() bool {
+		client.endpoints.mutex.Lock()
+		defer client.endpoints.mutex.Unlock()
+		return client.endpoints.endpoints[0].unhealthy
+	})
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, client.Get(context.Background(), "/dummy", &DummyResponse{}))
+	}
+
+	t.Logf("Should send all of them to the healthy server")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&unhealthyCount))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&healthyCount))
+}
+
+func TestClient_GetWithAbsoluteUrlAndEndpoints(t *testing.T) {
+	t.Logf("Given two HTTP servers returning 200 status")
+	var firstCount, secondCount int32
+	first := httptest.NewServer(endpointRequestHandler(200, 0, &firstCount))
+	defer first.Close()
+	second := httptest.NewServer(endpointRequestHandler(200, 0, &secondCount))
+	defer second.Close()
+
+	t.Logf("And given Client balancing only the first one")
+	client := newEndpointsClient(t, EndpointsConfig{Endpoints: []Endpoint{serverEndpoint(first, 0)}})
+	defer client.Close()
+
+	t.Logf("When calling GET for an absolute url of the second one")
+	err := client.Get(context.Background(), second.URL+"/dummy", &DummyResponse{})
+
+	t.Logf("Should send the request as it is")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&firstCount))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&secondCount))
+}
+
+func newEndpointsClient(t *testing.T, endpoints EndpointsConfig) *Client {
+	config := validClientConfig
+	config.Endpoints = endpoints
+	client, err := NewClient(config)
+	assert.NoError(t, err)
+	return client
+}
+
+func serverEndpoint(server *httptest.Server, weight int) Endpoint {
+	serverUrl, _ := url.Parse(server.URL)
+	return Endpoint{URL: *serverUrl, Weight: weight}
+}
+
+func endpointRequestHandler(statusCode int, delay time.Duration, callCount *int32) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(callCount, 1)
+		time.Sleep(delay)
+		res.WriteHeader(statusCode)
+		res.Write([]byte(`{"id":1,"title":"Jan"}`))
+	}
+}
+
+func TestClient_ReconfigureCarriesOverEndpointsAndMetrics(t *testing.T) {
+	t.Logf("Given HTTP server returning 200 status")
+	var count int32
+	server := httptest.NewServer(endpointRequestHandler(200, 0, &count))
+	defer server.Close()
+
+	t.Logf("And given Client balancing it and sending one request")
+	client := newEndpointsClient(t, EndpointsConfig{Endpoints: []Endpoint{serverEndpoint(server, 0)}})
+	assert.NoError(t, client.Get(context.Background(), "/dummy", &DummyResponse{}))
+
+	t.Logf("When reconfiguring Client with another timeout and closing the previous one")
+	config := client.config
+	config.Timeout = 2 * time.Second
+	next, err := client.Reconfigure(config)
+	assert.NoError(t, err)
+	defer next.Close()
+	client.Close()
+
+	t.Logf("Should keep the endpoints, the transport and the metrics")
+	assert.Same(t, client.endpoints, next.endpoints)
+	assert.Equal(t, client.client.Transport, next.client.Transport)
+	assert.Equal(t, 2*time.Second, next.client.Timeout)
+	assert.NoError(t, next.Get(context.Background(), "/dummy", &DummyResponse{}))
+	assert.Equal(t, int64(2), next.Metrics().Requests)
+	select {
+	case <-next.endpoints.stop:
+		t.Errorf("endpoints stopped by the previous Client")
+	default:
+	}
+}
+
+func TestClient_ReconfigureWithChangedEndpoints(t *testing.T) {
+	t.Logf("Given two HTTP servers returning 200 status")
+	var firstCount, secondCount int32
+	first := httptest.NewServer(endpointRequestHandler(200, 0, &firstCount))
+	defer first.Close()
+	second := httptest.NewServer(endpointRequestHandler(200, 0, &secondCount))
+	defer second.Close()
+
+	t.Logf("And given Client balancing the first one")
+	client := newEndpointsClient(t, EndpointsConfig{Endpoints: []Endpoint{serverEndpoint(first, 0)}})
+
+	t.Logf("When reconfiguring Client to balance the second one")
+	config := client.config
+	config.Endpoints = EndpointsConfig{Endpoints: []Endpoint{serverEndpoint(second, 0)}}
+	next, err := client.Reconfigure(config)
+	assert.NoError(t, err)
+	defer next.Close()
+	client.Close()
+
+	t.Logf("Should build new endpoints and stop the previous ones once the previous Client is closed")
+	assert.NotSame(t, client.endpoints, next.endpoints)
+	assert.NoError(t, next.Get(context.Background(), "/dummy", &DummyResponse{}))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&firstCount))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&secondCount))
+	<-client.endpoints.stop
+}
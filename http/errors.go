@@ -3,13 +3,39 @@ package http
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // Errors thrown by NewClient when ConfigClient has errors
 var (
-	TimeoutZeroError = errors.New("timeout has to be larger than 0ms")
+	TimeoutZeroError          = errors.New("timeout has to be larger than 0ms")
+	TotalTimeoutNegativeError = errors.New("total timeout cannot be negative")
+	InvalidCACertError        = errors.New("CA bundle does not contain any valid PEM certificate")
+	HedgingDelayZeroError     = errors.New("hedging requires delay or percentile larger than 0")
+	HedgingPercentileError    = errors.New("hedging percentile has to be between 0 and 100")
+	UnsupportedEncodingError  = errors.New("encoding has to be one of gzip, deflate or zstd")
+	UnknownContentTypeError   = errors.New("content type has to be handled by one of the codecs")
+	InvalidEndpointError      = errors.New("endpoint has to be an absolute url with a non-negative weight")
 )
 
+// Returned by Client.Stream when any of StreamOptions is negative
+var StreamOptionsNegativeError = errors.New("stream buffer, event size and reconnect delay cannot be negative")
+
+// Returned when the server responds to a stream with 204 status, which means it shouldn't be reconnected
+var StreamEndedError = errors.New("server ended the stream")
+
+// Wrapped by ClientError when a single server-sent event is larger than StreamOptions.MaxEventSize
+var EventTooLargeError = errors.New("event exceeds the maximum size")
+
+// Wrapped by DiscoveryError when the name has no SRV records
+var NoSRVRecordsError = errors.New("no SRV records found")
+
+// Wrapped by ClientError when a 2xx response other than 204 and 205 has an empty body, but the caller expects one
+var EmptyBodyError = errors.New("response body is empty")
+
+// Wrapped by ClientError when a decoded response body is larger than CompressionConfig.MaxDecodedSize
+var DecodedSizeExceededError = errors.New("decoded response body exceeds the maximum size")
+
 // Throw by the Client on unexpected non-http related issues like parsing, dialing or tls handshake issues
 type ClientError struct {
 	Url     string
@@ -34,3 +60,62 @@ type ClientHttpError struct {
 func (e *ClientHttpError) Error() string {
 	return fmt.Sprintf("failed to call %s due to HTTP error %d", e.Url, e.StatusCode)
 }
+
+// Throw by the Client whenever one of the timeouts expires, Budget says which one of them it was
+type TimeoutError struct {
+	Url    string
+	Budget TimeoutBudget
+	Limit  time.Duration
+	Err    error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("failed to call %s due to %s timeout of %s: %s", e.Url, e.Budget, e.Limit, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Throw by the Client when there's no codec for the Content-Type of the response
+type UnsupportedMediaTypeError struct {
+	Url         string
+	ContentType string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("failed to call %s due to unsupported media type '%s'", e.Url, e.ContentType)
+}
+
+// Throw by URLBuilder when a path param used by the route template is missing or its value is a dot segment
+type RouteParamError struct {
+	Route string
+	Param string
+	// Rejected value of the param, either "." or "..", empty when the param is missing
+	Value string
+}
+
+func (e *RouteParamError) Error() string {
+	if e.Value != "" {
+		return fmt.Sprintf("route %s has path param '%s' with dot segment '%s'", e.Route, e.Param, e.Value)
+	}
+	return fmt.Sprintf("route %s is missing path param '%s'", e.Route, e.Param)
+}
+
+// Throw by NewClient when the initial resolution of SRV records fails
+type DiscoveryError struct {
+	Name string
+	Err  error
+}
+
+func (e *DiscoveryError) Error() string {
+	return fmt.Sprintf("failed to resolve SRV records of %s: %s", e.Name, e.Err)
+}
+
+func (e *DiscoveryError) Unwrap() error {
+	return e.Err
+}
@@ -110,3 +110,32 @@ func ExampleClient_Post() {
 	}
 
 }
+
+func ExampleGet() {
+	// Basic, valid config
+	config := http.ClientConfig{
+		Retries: retry.RetriesConfig{MaxRetries: 3, Delay: time.Millisecond, Factor: 2},
+		Timeout: time.Second,
+	}
+
+	// New client
+	client, err := http.NewClient(config)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Expected response structure
+	type DummyResponse struct {
+		Id    int
+		Title string
+	}
+
+	// Actual call, the body is decoded into DummyResponse
+	dummyResponse, response, err := http.Get[DummyResponse](context.Background(), client, "http://localhost:8000")
+
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Print(dummyResponse.Title, response.StatusCode)
+}
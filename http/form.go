@@ -0,0 +1,198 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Codec for application/x-www-form-urlencoded bodies.
+//
+// Supports url.Values, map[string]string and flat structs, field names are taken from form tags,
+// then from json tags and finally from field names.
+type FormCodec struct{}
+
+func (FormCodec) ContentType() string {
+	return FormContentType
+}
+
+func (FormCodec) Marshal(value interface{}) ([]byte, error) {
+	switch typed := value.(type) {
+	case url.Values:
+		return []byte(typed.Encode()), nil
+	case *url.Values:
+		return []byte(typed.Encode()), nil
+	case map[string]string:
+		values := url.Values{}
+		for key, value := range typed {
+			values.Set(key, value)
+		}
+		return []byte(values.Encode()), nil
+	}
+
+	source := reflect.Indirect(reflect.ValueOf(value))
+	if source.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("form codec does not support %T", value)
+	}
+	values := url.Values{}
+	for i := 0; i < source.NumField(); i++ {
+		field := source.Type().Field(i)
+		name, omitEmpty := formFieldName(field)
+		if name == "" {
+			continue
+		}
+		fieldValue := source.Field(i)
+		if omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+		formatted, err := formatFormValue(fieldValue)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = formatted
+	}
+	return []byte(values.Encode()), nil
+}
+
+func (FormCodec) Unmarshal(data []byte, value interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	switch typed := value.(type) {
+	case *url.Values:
+		*typed = values
+		return nil
+	case *map[string]string:
+		*typed = map[string]string{}
+		for key := range values {
+			(*typed)[key] = values.Get(key)
+		}
+		return nil
+	}
+
+	target := reflect.ValueOf(value)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("form codec does not support %T", value)
+	}
+	target = target.Elem()
+	for i := 0; i < target.NumField(); i++ {
+		name, _ := formFieldName(target.Type().Field(i))
+		if name == "" || len(values[name]) == 0 {
+			continue
+		}
+		if err := parseFormValue(target.Field(i), values[name]); err != nil {
+			return fmt.Errorf("form field %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func formFieldName(field reflect.StructField) (string, bool) {
+	return taggedFieldName(field, "form")
+}
+
+// Name of the field taken from the given tag, then from json tag and finally from its name,
+// along with its omitempty option. Unexported and "-" fields have no name
+func taggedFieldName(field reflect.StructField, tagName string) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	tag, ok := field.Tag.Lookup(tagName)
+	if !ok {
+		tag, ok = field.Tag.Lookup("json")
+	}
+	if !ok {
+		return field.Name, false
+	}
+	parts := strings.Split(tag, ",")
+	if parts[0] == "-" {
+		return "", false
+	}
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	omitEmpty := false
+	for _, option := range parts[1:] {
+		omitEmpty = omitEmpty || option == "omitempty"
+	}
+	return name, omitEmpty
+}
+
+func formatFormValue(value reflect.Value) ([]string, error) {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		var formatted []string
+		for i := 0; i < value.Len(); i++ {
+			item, err := formatFormValue(value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			formatted = append(formatted, item...)
+		}
+		return formatted, nil
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil, nil
+		}
+		return formatFormValue(value.Elem())
+	case reflect.String:
+		return []string{value.String()}, nil
+	case reflect.Bool:
+		return []string{strconv.FormatBool(value.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(value.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{strconv.FormatUint(value.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return []string{strconv.FormatFloat(value.Float(), 'f', -1, 64)}, nil
+	default:
+		return nil, fmt.Errorf("form codec does not support %s", value.Type())
+	}
+}
+
+func parseFormValue(target reflect.Value, values []string) error {
+	switch target.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(target.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := parseFormValue(slice.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+	case reflect.Ptr:
+		item := reflect.New(target.Type().Elem())
+		if err := parseFormValue(item.Elem(), values); err != nil {
+			return err
+		}
+		target.Set(item)
+		return nil
+	case reflect.String:
+		target.SetString(values[0])
+		return nil
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(values[0])
+		target.SetBool(parsed)
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(values[0], 10, target.Type().Bits())
+		target.SetInt(parsed)
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(values[0], 10, target.Type().Bits())
+		target.SetUint(parsed)
+		return err
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(values[0], target.Type().Bits())
+		target.SetFloat(parsed)
+		return err
+	default:
+		return fmt.Errorf("form codec does not support %s", target.Type())
+	}
+}
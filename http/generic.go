@@ -0,0 +1,46 @@
+package http
+
+import (
+	"context"
+	corehttp "net/http"
+)
+
+// Runs GET HTTP query for provided url and returns the response body decoded into T along with response's metadata.
+//
+// Errors are the same as the ones returned by Client.Get, in case of an error zero value of T is returned.
+func Get[T any](ctx context.Context, client *Client, url string) (T, *Response, error) {
+	return do[T](ctx, client, corehttp.MethodGet, url, nil)
+}
+
+// Runs DELETE HTTP query for provided url and returns the response body decoded into T along with response's metadata.
+//
+// Errors are the same as the ones returned by Client.Delete, in case of an error zero value of T is returned.
+func Delete[T any](ctx context.Context, client *Client, url string) (T, *Response, error) {
+	return do[T](ctx, client, corehttp.MethodDelete, url, nil)
+}
+
+// Runs PUT HTTP query for provided url with requestBody and returns the response body decoded into Resp
+// along with response's metadata.
+//
+// Errors are the same as the ones returned by Client.Put, in case of an error zero value of Resp is returned.
+func Put[Req any, Resp any](ctx context.Context, client *Client, url string, requestBody Req) (Resp, *Response, error) {
+	return do[Resp](ctx, client, corehttp.MethodPut, url, requestBody)
+}
+
+// Runs POST HTTP query for provided url with requestBody and returns the response body decoded into Resp
+// along with response's metadata.
+//
+// Errors are the same as the ones returned by Client.Post, in case of an error zero value of Resp is returned.
+func Post[Req any, Resp any](ctx context.Context, client *Client, url string, requestBody Req) (Resp, *Response, error) {
+	return do[Resp](ctx, client, corehttp.MethodPost, url, requestBody)
+}
+
+func do[T any](ctx context.Context, client *Client, method string, url string, requestBody interface{}) (T, *Response, error) {
+	var responseBody T
+	response, err := client.do(ctx, method, url, requestBody, &responseBody)
+	if err != nil {
+		var zero T
+		return zero, response, err
+	}
+	return responseBody, response, nil
+}
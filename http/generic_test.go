@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	t.Logf("Given HTTP server returning 200 status with ETag")
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("ETag", `"v1"`)
+		res.Write([]byte(`{"id":1,"title":"Jan"}`))
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling Get[DummyResponse]")
+	dummyResponse, response, err := Get[DummyResponse](context.Background(), client, server.URL)
+
+	t.Logf("Should return DummyResponse with response's metadata")
+	assert.NoError(t, err)
+	assert.Equal(t, DummyResponse{Title: "Jan", Id: 1}, dummyResponse)
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, `"v1"`, response.Header.Get("ETag"))
+}
+
+func TestGetWithResponseBodyParsingError(t *testing.T) {
+	t.Logf("Given HTTP server returning 200 status with invalid body")
+	callCount := make(map[string]int)
+	server := httptest.NewServer(requestHandlerWithBody(200, &callCount, "aa"))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling Get[DummyResponse]")
+	dummyResponse, _, err := Get[DummyResponse](context.Background(), client, server.URL)
+
+	t.Logf("Should return ClientError with parsing message and zero DummyResponse")
+	assert.EqualError(t, err, (&ClientError{Message: "parsing error", Url: server.URL, Err: &json.UnmarshalTypeError{
+		Value:  "string",
+		Type:   reflect.TypeOf(dummyResponse),
+		Offset: 4,
+	}}).Error())
+	assert.Equal(t, DummyResponse{}, dummyResponse)
+}
+
+func TestGetWithHttpError(t *testing.T) {
+	t.Logf("Given HTTP server returning 404 status")
+	callCount := make(map[string]int)
+	server := httptest.NewServer(requestHandler(404, &callCount))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling Get[[]DummyResponse]")
+	dummyResponses, response, err := Get[[]DummyResponse](context.Background(), client, server.URL)
+
+	t.Logf("Should return ClientHttpError along with response's metadata")
+	assert.EqualError(t, err, (&ClientHttpError{Url: server.URL, StatusCode: 404}).Error())
+	assert.Nil(t, dummyResponses)
+	assert.Equal(t, 404, response.StatusCode)
+}
+
+func TestPostAndDelete(t *testing.T) {
+	t.Logf("Given HTTP server echoing request's method and title")
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		var request DummyRequest
+		json.NewDecoder(req.Body).Decode(&request)
+		res.WriteHeader(201)
+		json.NewEncoder(res).Encode(DummyResponse{Id: 1, Title: req.Method + request.Title})
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling Post[DummyRequest, DummyResponse] and Delete[DummyResponse]")
+	posted, postResponse, postErr := Post[DummyRequest, DummyResponse](context.Background(), client, server.URL, DummyRequest{Title: "Jan"})
+	deleted, deleteResponse, deleteErr := Delete[DummyResponse](context.Background(), client, server.URL)
+
+	t.Logf("Should return decoded responses with 201 status")
+	assert.NoError(t, postErr)
+	assert.NoError(t, deleteErr)
+	assert.Equal(t, DummyResponse{Id: 1, Title: "POSTJan"}, posted)
+	assert.Equal(t, DummyResponse{Id: 1, Title: "DELETE"}, deleted)
+	assert.Equal(t, 201, postResponse.StatusCode)
+	assert.Equal(t, 201, deleteResponse.StatusCode)
+}
@@ -0,0 +1,237 @@
+package http
+
+import (
+	"context"
+	"io"
+	"log"
+	"math"
+	corehttp "net/http"
+	"sort"
+	"sync"
+	"test2/clock"
+	"time"
+)
+
+const (
+	defaultHedgingMinSamples = 20
+	hedgingSamplesWindow     = 128
+)
+
+// Configures hedging of idempotent requests (GET).
+//
+// If the request hasn't been answered within a delay, another one is sent and whichever finishes first is taken,
+// the remaining ones are cancelled. Hedging is disabled when MaxHedges is zero.
+type HedgingConfig struct {
+	// Maximum number of additional requests sent within a single attempt
+	MaxHedges int
+	// Fixed delay after which the next request is sent
+	Delay time.Duration
+	// Optional percentile (0-100] of observed latencies used as the delay instead of Delay,
+	// Delay is used until MinSamples latencies are observed
+	Percentile float64
+	// Defaults to 20
+	MinSamples int
+}
+
+func (c HedgingConfig) enabled() bool {
+	return c.MaxHedges > 0
+}
+
+func validateHedging(config HedgingConfig) error {
+	if !config.enabled() {
+		return nil
+	}
+	if config.Percentile < 0 || config.Percentile > 100 {
+		return HedgingPercentileError
+	}
+	if config.Delay <= 0 && config.Percentile == 0 {
+		return HedgingDelayZeroError
+	}
+	return nil
+}
+
+type hedger struct {
+	config    HedgingConfig
+	metrics   *metrics
+	latencies *latencies
+	clock     clock.Clock
+	logging   bool
+}
+
+func newHedger(config HedgingConfig, metrics *metrics, latencies *latencies, clock clock.Clock, logging bool) *hedger {
+	if config.MinSamples <= 0 {
+		config.MinSamples = defaultHedgingMinSamples
+	}
+	return &hedger{
+		config:    config,
+		metrics:   metrics,
+		latencies: latencies,
+		clock:     clock,
+		logging:   logging,
+	}
+}
+
+func (h *hedger) delay() time.Duration {
+	if h.config.Percentile > 0 {
+		if delay, ok := h.latencies.percentile(h.config.Percentile, h.config.MinSamples); ok {
+			return delay
+		}
+	}
+	return h.config.Delay
+}
+
+type hedgedResult struct {
+	sent
+	index  int
+	cancel context.CancelFunc
+}
+
+func (r hedgedResult) answered() bool {
+	return r.err == nil && r.response.StatusCode < 500
+}
+
+// Sends the request with client and hedges it after the delay, the first answered (non 5xx) response is returned,
+// otherwise the last failed one. Every request is resolved against its own endpoint, avoiding the one of
+// the previous request (or avoid for the first one) when possible, so hedges go to different replicas
+func (h *hedger) do(client *Client, request *corehttp.Request, avoid *endpoint) sent {
+	delay := h.delay()
+	if delay <= 0 {
+		attempt, picked, err := client.prepare(request.Context(), request, avoid)
+		if err != nil {
+			return sent{url: request.URL.String(), err: err}
+		}
+		return h.send(client, attempt, picked)
+	}
+
+	results := make(chan hedgedResult, h.config.MaxHedges+1)
+	var cancels []context.CancelFunc
+	send := func() {
+		ctx, cancel := context.WithCancel(request.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		attempt, picked, err := client.prepare(ctx, request, avoid)
+		if err != nil {
+			results <- hedgedResult{sent: sent{url: request.URL.String(), err: err}, index: index, cancel: cancel}
+			return
+		}
+		if picked != nil {
+			avoid = picked
+		}
+		go func() {
+			results <- hedgedResult{sent: h.send(client, attempt, picked), index: index, cancel: cancel}
+		}()
+	}
+
+	send()
+	inFlight, hedges := 1, 0
+	timer := h.clock.NewTimer(delay)
+	defer timer.Stop()
+
+	var last hedgedResult
+	for inFlight > 0 {
+		select {
+		case <-timer.C():
+			if h.logging {
+				log.Printf("Hedging request to [%s] [%s] after [%s] \n", request.Method, request.URL.String(), delay.String())
+			}
+			send()
+			inFlight++
+			hedges++
+			h.metrics.hedgeSent()
+			if hedges < h.config.MaxHedges {
+				timer.Reset(delay)
+			}
+		case result := <-results:
+			inFlight--
+			if result.answered() {
+				for index, cancel := range cancels {
+					if index != result.index {
+						cancel()
+					}
+				}
+				go discardResults(results, inFlight)
+				if result.index > 0 {
+					h.metrics.hedgeWon()
+				}
+				result.response.Body = &cancelOnClose{ReadCloser: result.response.Body, cancel: result.cancel}
+				return result.sent
+			}
+			closeResponse(last.response)
+			if last.cancel != nil {
+				last.cancel()
+			}
+			last = result
+		}
+	}
+	if last.response != nil {
+		last.response.Body = &cancelOnClose{ReadCloser: last.response.Body, cancel: last.cancel}
+	} else {
+		last.cancel()
+	}
+	return last.sent
+}
+
+func (h *hedger) send(client *Client, attempt *corehttp.Request, picked *endpoint) sent {
+	startTime := h.clock.Now()
+	result := client.sendPrepared(attempt, picked)
+	if result.err == nil && result.response.StatusCode < 500 {
+		h.latencies.add(h.clock.Now().Sub(startTime))
+	}
+	return result
+}
+
+func discardResults(results chan hedgedResult, count int) {
+	for i := 0; i < count; i++ {
+		result := <-results
+		closeResponse(result.response)
+		result.cancel()
+	}
+}
+
+// Cancels the context of a hedged request once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// Window of recently observed latencies
+type latencies struct {
+	mutex   sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func (l *latencies) add(latency time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.samples) < hedgingSamplesWindow {
+		l.samples = append(l.samples, latency)
+		return
+	}
+	l.samples[l.next] = latency
+	l.next = (l.next + 1) % hedgingSamplesWindow
+}
+
+func (l *latencies) percentile(percentile float64, minSamples int) (time.Duration, bool) {
+	l.mutex.Lock()
+	if len(l.samples) < minSamples {
+		l.mutex.Unlock()
+		return 0, false
+	}
+	sorted := make([]time.Duration, len(l.samples))
+	copy(sorted, l.samples)
+	l.mutex.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(math.Ceil(percentile/100*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index], true
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"test2/http/retry"
+	"testing"
+	"time"
+)
+
+func TestNewClientWithInvalidHedgingConfig(t *testing.T) {
+	testCases := []struct {
+		Hedging       HedgingConfig
+		ExpectedError error
+	}{
+		{Hedging: HedgingConfig{MaxHedges: 1}, ExpectedError: HedgingDelayZeroError},
+		{Hedging: HedgingConfig{MaxHedges: 1, Percentile: 101}, ExpectedError: HedgingPercentileError},
+		{Hedging: HedgingConfig{MaxHedges: 1, Delay: time.Millisecond, Percentile: -1}, ExpectedError: HedgingPercentileError},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("Given ClientConfig with invalid hedging=%+v", testCase.Hedging)
+		config := validClientConfig
+		config.Hedging = testCase.Hedging
+
+		t.Logf("When creating Client")
+		client, err := NewClient(config)
+
+		t.Logf("Should return '%s' error", testCase.ExpectedError)
+		assert.Equal(t, testCase.ExpectedError, err)
+		assert.Nil(t, client)
+	}
+}
+
+func TestClient_GetWithHedging(t *testing.T) {
+	testCases := []struct {
+		Name               string
+		SlowRequests       int32
+		MaxHedges          int
+		ExpectedCallCount  int32
+		ExpectedHedgesSent int64
+		ExpectedHedgeWins  int64
+	}{
+		{Name: "fast response", SlowRequests: 0, MaxHedges: 1, ExpectedCallCount: 1, ExpectedHedgesSent: 0, ExpectedHedgeWins: 0},
+		{Name: "slow first response", SlowRequests: 1, MaxHedges: 1, ExpectedCallCount: 2, ExpectedHedgesSent: 1, ExpectedHedgeWins: 1},
+		{Name: "slow responses", SlowRequests: 3, MaxHedges: 2, ExpectedCallCount: 3, ExpectedHedgesSent: 2, ExpectedHedgeWins: 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("Given HTTP server with %s", testCase.Name)
+		var callCount int32
+		server := httptest.NewServer(hedgingRequestHandler(testCase.SlowRequests, 300*time.Millisecond, &callCount))
+
+		t.Logf("And given Client hedging after 50ms up to %d times", testCase.MaxHedges)
+		config := validClientConfig
+		config.Hedging = HedgingConfig{MaxHedges: testCase.MaxHedges, Delay: 50 * time.Millisecond}
+		client, _ := NewClient(config)
+
+		t.Logf("When calling GET")
+		var dummyResponse DummyResponse
+		err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+		t.Logf("Should return DummyResponse and send %d hedges", testCase.ExpectedHedgesSent)
+		assert.NoError(t, err)
+		assert.Equal(t, DummyResponse{Title: "Jan", Id: 1}, dummyResponse)
+		assert.Equal(t, testCase.ExpectedCallCount, atomic.LoadInt32(&callCount))
+		assert.Equal(t, testCase.ExpectedHedgesSent, client.Metrics().HedgesSent)
+		assert.Equal(t, testCase.ExpectedHedgeWins, client.Metrics().HedgeWins)
+		server.Close()
+	}
+}
+
+func TestClient_PostIsNotHedged(t *testing.T) {
+	t.Logf("Given HTTP server with slow first response")
+	var callCount int32
+	server := httptest.NewServer(hedgingRequestHandler(1, 100*time.Millisecond, &callCount))
+	defer server.Close()
+
+	t.Logf("And given Client hedging after 10ms")
+	config := validClientConfig
+	config.Hedging = HedgingConfig{MaxHedges: 1, Delay: 10 * time.Millisecond}
+	client, _ := NewClient(config)
+
+	t.Logf("When calling POST")
+	var dummyResponse DummyResponse
+	err := client.Post(context.Background(), server.URL, &DummyRequest{Title: "Jan"}, &dummyResponse)
+
+	t.Logf("Should not send any hedges")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&callCount))
+	assert.Equal(t, int64(0), client.Metrics().HedgesSent)
+}
+
+func TestClient_GetWithHedgingAndServerErrors(t *testing.T) {
+	t.Logf("Given HTTP server returning 503 status")
+	callCount := make(map[string]int)
+	server := httptest.NewServer(requestHandler(503, &callCount))
+	defer server.Close()
+
+	t.Logf("And given Client hedging after 50ms")
+	client, _ := NewClient(ClientConfig{
+		Timeout: time.Second,
+		Retries: retry.RetriesConfig{MaxRetries: 2, Delay: time.Millisecond, Factor: 2},
+		Hedging: HedgingConfig{MaxHedges: 1, Delay: 50 * time.Millisecond},
+	})
+
+	t.Logf("When calling GET")
+	var dummyResponse DummyResponse
+	err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+	t.Logf("Should retry without hedging and return ClientHttpError")
+	assert.EqualError(t, err, (&ClientHttpError{Url: server.URL, StatusCode: 503}).Error())
+	assert.Equal(t, 3, callCount["/"])
+	assert.Equal(t, int64(0), client.Metrics().HedgesSent)
+	assert.Equal(t, int64(3), client.Metrics().Attempts)
+}
+
+func TestClient_GetWithHedgingAcrossEndpoints(t *testing.T) {
+	t.Logf("Given slow and fast HTTP servers")
+	var slowCount, fastCount int32
+	slow := httptest.NewServer(endpointRequestHandler(200, 300*time.Millisecond, &slowCount))
+	defer slow.Close()
+	fast := httptest.NewServer(endpointRequestHandler(200, 0, &fastCount))
+	defer fast.Close()
+
+	t.Logf("And given Client balancing between them by latency and hedging after 50ms")
+	config := validClientConfig
+	config.Endpoints = EndpointsConfig{Endpoints: []Endpoint{serverEndpoint(slow, 0), serverEndpoint(fast, 0)}, Balancing: LeastLatencyBalancing}
+	config.Hedging = HedgingConfig{MaxHedges: 1, Delay: 50 * time.Millisecond}
+	client, _ := NewClient(config)
+	defer client.Close()
+
+	t.Logf("When calling GET for a relative url")
+	err := client.Get(context.Background(), "/dummy", &DummyResponse{})
+
+	t.Logf("Should send the hedge to the other endpoint and take its response")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&slowCount))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fastCount))
+	assert.Equal(t, int64(1), client.Metrics().HedgeWins)
+}
+
+func TestLatenciesPercentile(t *testing.T) {
+	t.Logf("Given 100 latencies from 1ms to 100ms")
+	latencies := &latencies{}
+	for i := 1; i <= 100; i++ {
+		latencies.add(time.Duration(i) * time.Millisecond)
+	}
+
+	testCases := []struct {
+		Percentile    float64
+		MinSamples    int
+		ExpectedDelay time.Duration
+		ExpectedOk    bool
+	}{
+		{Percentile: 50, MinSamples: 10, ExpectedDelay: 50 * time.Millisecond, ExpectedOk: true},
+		{Percentile: 95, MinSamples: 10, ExpectedDelay: 95 * time.Millisecond, ExpectedOk: true},
+		{Percentile: 100, MinSamples: 10, ExpectedDelay: 100 * time.Millisecond, ExpectedOk: true},
+		{Percentile: 95, MinSamples: 101, ExpectedDelay: 0, ExpectedOk: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("When calculating p%0.f with minSamples=%d", testCase.Percentile, testCase.MinSamples)
+		delay, ok := latencies.percentile(testCase.Percentile, testCase.MinSamples)
+
+		t.Logf("Should return %s", testCase.ExpectedDelay)
+		assert.Equal(t, testCase.ExpectedOk, ok)
+		assert.Equal(t, testCase.ExpectedDelay, delay)
+	}
+}
+
+// Responds after the delay to the first slowRequests requests, to the remaining ones immediately
+func hedgingRequestHandler(slowRequests int32, delay time.Duration, callCount *int32) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(callCount, 1) <= slowRequests {
+			select {
+			case <-time.After(delay):
+			case <-req.Context().Done():
+				return
+			}
+		}
+		res.Write([]byte(`{"id":1,"title":"Jan"}`))
+	}
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"test2/http/retry"
+)
+
+// Snapshot of the Client's counters, returned by Client.Metrics
+type Metrics struct {
+	// Number of calls made through the Client (Get, Post, Delete)
+	Requests int64
+	// Number of HTTP requests sent, including retries
+	Attempts int64
+	// Number of additional requests sent by hedging
+	HedgesSent int64
+	// Number of times a hedged request answered before the original one
+	HedgeWins int64
+	// Number of GET requests which joined an identical request already in flight
+	Coalesced int64
+	// Number of retries skipped as the retry budget was exhausted
+	RetriesSkipped int64
+	// Number of times an endpoint was ejected after consecutive failures
+	Ejections int64
+	// Calls labelled with WithRoute by their route template
+	Routes map[string]RouteMetrics
+}
+
+// Counters of the calls of a single route
+type RouteMetrics struct {
+	// Number of calls made with the route
+	Requests int64
+	// Number of calls which returned an error
+	Errors int64
+}
+
+type metrics struct {
+	requests   int64
+	attempts   int64
+	hedgesSent int64
+	hedgeWins  int64
+	coalesced  int64
+	ejections  int64
+	// Counted here rather than by retry.Retry, so retries of the Clients replaced by Client.Reconfigure
+	// don't have to be kept around
+	retriesSkipped int64
+
+	routesMutex sync.Mutex
+	routes      map[string]*RouteMetrics
+}
+
+func (m *metrics) request() {
+	atomic.AddInt64(&m.requests, 1)
+}
+
+// Counts the finished call of route, calls without a route are not counted
+func (m *metrics) routeFinished(route string, err error) {
+	if route == "" {
+		return
+	}
+	m.routesMutex.Lock()
+	defer m.routesMutex.Unlock()
+	if m.routes == nil {
+		m.routes = map[string]*RouteMetrics{}
+	}
+	counters, ok := m.routes[route]
+	if !ok {
+		counters = &RouteMetrics{}
+		m.routes[route] = counters
+	}
+	counters.Requests++
+	if err != nil {
+		counters.Errors++
+	}
+}
+
+func (m *metrics) attempt() {
+	atomic.AddInt64(&m.attempts, 1)
+}
+
+func (m *metrics) hedgeSent() {
+	atomic.AddInt64(&m.hedgesSent, 1)
+}
+
+func (m *metrics) hedgeWon() {
+	atomic.AddInt64(&m.hedgeWins, 1)
+}
+
+func (m *metrics) coalesce() {
+	atomic.AddInt64(&m.coalesced, 1)
+}
+
+func (m *metrics) eject() {
+	atomic.AddInt64(&m.ejections, 1)
+}
+
+// Counts the retry skipped when err is the retries giving up on the exhausted budget
+func (m *metrics) retried(err error) {
+	if errors.Is(err, retry.RetryBudgetExhaustedError) {
+		atomic.AddInt64(&m.retriesSkipped, 1)
+	}
+}
+
+func (m *metrics) snapshot() Metrics {
+	m.routesMutex.Lock()
+	var routes map[string]RouteMetrics
+	if len(m.routes) > 0 {
+		routes = make(map[string]RouteMetrics, len(m.routes))
+		for route, counters := range m.routes {
+			routes[route] = *counters
+		}
+	}
+	m.routesMutex.Unlock()
+	return Metrics{
+		Routes:         routes,
+		RetriesSkipped: atomic.LoadInt64(&m.retriesSkipped),
+		Requests:       atomic.LoadInt64(&m.requests),
+		Attempts:       atomic.LoadInt64(&m.attempts),
+		HedgesSent:     atomic.LoadInt64(&m.hedgesSent),
+		HedgeWins:      atomic.LoadInt64(&m.hedgeWins),
+		Coalesced:      atomic.LoadInt64(&m.coalesced),
+		Ejections:      atomic.LoadInt64(&m.ejections),
+	}
+}
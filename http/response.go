@@ -0,0 +1,45 @@
+package http
+
+import (
+	corehttp "net/http"
+	"net/url"
+	"time"
+)
+
+// Metadata of a response, returned along with its decoded body or along with ClientHttpError of its status
+type Response struct {
+	StatusCode int
+	Header     corehttp.Header
+	// Number of attempts made, including the last one
+	Attempts int
+	// Time spent on the whole operation, including retries and delays between them
+	Duration time.Duration
+	// Url of the request which produced the response, differs from the requested one after redirects
+	URL *url.URL
+}
+
+func newResponse(result *fetched, duration time.Duration) *Response {
+	return &Response{
+		StatusCode: result.statusCode,
+		Header:     result.header.Clone(),
+		Attempts:   result.attempts,
+		Duration:   duration,
+		URL:        result.url,
+	}
+}
+
+// Returns Location header resolved against the final url, nil when the header is missing or invalid
+func (r *Response) Location() *url.URL {
+	location := r.Header.Get("Location")
+	if location == "" {
+		return nil
+	}
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return nil
+	}
+	if r.URL == nil {
+		return parsed
+	}
+	return r.URL.ResolveReference(parsed)
+}
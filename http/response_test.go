@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClient_DoWithRetriedRequest(t *testing.T) {
+	t.Logf("Given HTTP server returning 500 status once and then 201 status with Location")
+	var callCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&callCount, 1) == 1 {
+			res.WriteHeader(500)
+			return
+		}
+		res.Header().Set("Location", "/dummy/1")
+		res.WriteHeader(201)
+		res.Write([]byte(`{"id":1,"title":"Jan"}`))
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling Do with POST method")
+	var dummyResponse DummyResponse
+	response, err := client.Do(context.Background(), "POST", server.URL+"/dummy", DummyRequest{Title: "Jan"}, &dummyResponse)
+
+	t.Logf("Should return response's metadata with 2 attempts")
+	assert.NoError(t, err)
+	assert.Equal(t, DummyResponse{Id: 1, Title: "Jan"}, dummyResponse)
+	assert.Equal(t, 201, response.StatusCode)
+	assert.Equal(t, 2, response.Attempts)
+	assert.True(t, response.Duration > 0)
+	assert.Equal(t, server.URL+"/dummy", response.URL.String())
+	assert.Equal(t, server.URL+"/dummy/1", response.Location().String())
+}
+
+func TestClient_DoWithRedirect(t *testing.T) {
+	t.Logf("Given HTTP server redirecting /old to /new")
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/old" {
+			http.Redirect(res, req, "/new", http.StatusMovedPermanently)
+			return
+		}
+		res.Write([]byte(`{"id":1,"title":"Jan"}`))
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling Do with GET method for /old")
+	var dummyResponse DummyResponse
+	response, err := client.Do(context.Background(), "GET", server.URL+"/old", nil, &dummyResponse)
+
+	t.Logf("Should return final url of the redirect")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, 1, response.Attempts)
+	assert.Equal(t, server.URL+"/new", response.URL.String())
+	assert.Nil(t, response.Location())
+}
+
+func TestClient_DoWithHttpError(t *testing.T) {
+	t.Logf("Given HTTP server returning 404 status")
+	callCount := make(map[string]int)
+	server := httptest.NewServer(requestHandler(404, &callCount))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling Do with GET method")
+	response, err := client.Do(context.Background(), "GET", server.URL, nil, &DummyResponse{})
+
+	t.Logf("Should return ClientHttpError along with response's metadata")
+	assert.EqualError(t, err, (&ClientHttpError{Url: server.URL, StatusCode: 404}).Error())
+	assert.Equal(t, 404, response.StatusCode)
+	assert.Equal(t, 1, response.Attempts)
+	assert.Equal(t, server.URL, response.URL.String())
+}
+
+func TestResponse_Location(t *testing.T) {
+	finalUrl, _ := url.Parse("http://localhost:8000/api/inventory")
+	tests := []struct {
+		location string
+		expected string
+	}{
+		{location: "http://example.com/inventory/1", expected: "http://example.com/inventory/1"},
+		{location: "/api/inventory/1", expected: "http://localhost:8000/api/inventory/1"},
+		{location: "inventory/1", expected: "http://localhost:8000/api/inventory/1"},
+	}
+	for _, test := range tests {
+		t.Logf("Given Response with Location header %s", test.location)
+		response := &Response{Header: http.Header{"Location": []string{test.location}}, URL: finalUrl}
+
+		t.Logf("Should resolve it to %s", test.expected)
+		assert.Equal(t, test.expected, response.Location().String())
+	}
+}
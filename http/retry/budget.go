@@ -0,0 +1,118 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultBudgetWindow = 10 * time.Second
+	budgetBuckets       = 10
+)
+
+type BudgetConfig struct {
+	// Fraction of requests which may be retried within the window, e.g. 0.1 allows one retry per 10 requests
+	Ratio float64
+	// Retries allowed every second regardless of the ratio, so clients with little traffic can still retry
+	MinPerSecond float64
+	// Sliding window in which requests and retries are counted, defaults to 10s
+	Window time.Duration
+}
+
+// Snapshot of the Budget's counters, returned by Budget.Stats
+type BudgetStats struct {
+	// Number of requests and retries within the current window
+	Requests int64
+	Retries  int64
+	// Total number of retries which were skipped as the budget was exhausted
+	Exhausted int64
+}
+
+// Limits retries to a fraction of requests over a sliding window, so during an incident retries can't multiply
+// the load by MaxRetries.
+//
+// A single Budget can be shared by many Retry instances (and so clients) through RetriesConfig.Budget.
+// The window is driven by RetriesConfig.Clock of the Retry recording requests and retries.
+type Budget struct {
+	mutex       sync.Mutex
+	config      BudgetConfig
+	bucketWidth time.Duration
+	buckets     [budgetBuckets]budgetBucket
+	exhausted   int64
+	// Time of the latest request or retry, Stats counts the window up to it
+	latest time.Time
+}
+
+// Requests and retries counted within a slice of the window, epoch tells which slice it is
+type budgetBucket struct {
+	epoch    int64
+	requests int64
+	retries  int64
+}
+
+// Constructs new Budget from BudgetConfig
+// If BudgetConfig.Ratio or BudgetConfig.MinPerSecond is below zero, it returns BudgetNegativeError
+func NewBudget(config BudgetConfig) (*Budget, error) {
+	if config.Ratio < 0 || config.MinPerSecond < 0 {
+		return nil, BudgetNegativeError
+	}
+	if config.Window <= 0 {
+		config.Window = defaultBudgetWindow
+	}
+	return &Budget{config: config, bucketWidth: config.Window / budgetBuckets}, nil
+}
+
+// Records a request made at now, every request extends the budget by Ratio
+func (b *Budget) request(now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.current(now).requests++
+}
+
+// Withdraws a retry made at now from the budget, returns false when it's exhausted
+func (b *Budget) withdraw(now time.Time) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	requests, retries := b.sum(now)
+	allowed := b.config.MinPerSecond*b.config.Window.Seconds() + b.config.Ratio*float64(requests)
+	if float64(retries)+1 > allowed {
+		b.exhausted++
+		return false
+	}
+	b.current(now).retries++
+	return true
+}
+
+// Returns a snapshot of the Budget's counters as of its latest request or retry
+func (b *Budget) Stats() BudgetStats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	requests, retries := b.sum(b.latest)
+	return BudgetStats{Requests: requests, Retries: retries, Exhausted: b.exhausted}
+}
+
+// Bucket of the current slice of the window, reset when it holds counters of an expired slice
+func (b *Budget) current(now time.Time) *budgetBucket {
+	if now.After(b.latest) {
+		b.latest = now
+	}
+	epoch := now.UnixNano() / int64(b.bucketWidth)
+	bucket := &b.buckets[epoch%budgetBuckets]
+	if bucket.epoch != epoch {
+		*bucket = budgetBucket{epoch: epoch}
+	}
+	return bucket
+}
+
+func (b *Budget) sum(now time.Time) (int64, int64) {
+	epoch := now.UnixNano() / int64(b.bucketWidth)
+	var requests, retries int64
+	for _, bucket := range b.buckets {
+		if epoch-bucket.epoch < budgetBuckets {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+	return requests, retries
+}
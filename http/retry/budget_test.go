@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"test2/clock"
+	"testing"
+	"time"
+)
+
+func TestNewBudgetWithInvalidConfig(t *testing.T) {
+	for _, config := range []BudgetConfig{{Ratio: -0.1}, {MinPerSecond: -1}} {
+		t.Logf("Given invalid BudgetConfig ratio=%0.2f minPerSecond=%0.2f", config.Ratio, config.MinPerSecond)
+
+		t.Logf("When creating Budget")
+		budget, err := NewBudget(config)
+
+		t.Logf("Should return BudgetNegativeError")
+		assert.Equal(t, BudgetNegativeError, err)
+		assert.Nil(t, budget)
+	}
+}
+
+func TestBudgetWithRatio(t *testing.T) {
+	t.Logf("Given Budget allowing retries of 10%% of requests")
+	budget, _ := NewBudget(BudgetConfig{Ratio: 0.1})
+	now := time.Unix(0, 0)
+
+	t.Logf("When recording 20 requests and withdrawing 3 retries")
+	for i := 0; i < 20; i++ {
+		budget.request(now)
+	}
+	withdrawn := []bool{budget.withdraw(now), budget.withdraw(now), budget.withdraw(now)}
+
+	t.Logf("Should allow only 2 of them")
+	assert.Equal(t, []bool{true, true, false}, withdrawn)
+	assert.Equal(t, BudgetStats{Requests: 20, Retries: 2, Exhausted: 1}, budget.Stats())
+}
+
+func TestBudgetWithMinPerSecond(t *testing.T) {
+	t.Logf("Given Budget allowing 1 retry per second within 2s window")
+	budget, _ := NewBudget(BudgetConfig{MinPerSecond: 1, Window: 2 * time.Second})
+	now := time.Unix(0, 0)
+
+	t.Logf("When withdrawing 3 retries without any requests")
+	withdrawn := []bool{budget.withdraw(now), budget.withdraw(now), budget.withdraw(now)}
+
+	t.Logf("Should allow only 2 of them")
+	assert.Equal(t, []bool{true, true, false}, withdrawn)
+}
+
+func TestBudgetWithSlidingWindow(t *testing.T) {
+	t.Logf("Given Budget allowing retries of 50%% of requests within 10s window")
+	budget, _ := NewBudget(BudgetConfig{Ratio: 0.5, Window: 10 * time.Second})
+	now := time.Unix(0, 0)
+
+	t.Logf("And given 2 requests with 1 retry recorded")
+	budget.request(now)
+	budget.request(now)
+	assert.True(t, budget.withdraw(now))
+
+	t.Logf("When the window slides by 5s and then by another 6s")
+	now = now.Add(5 * time.Second)
+	afterFiveSeconds := budget.withdraw(now)
+	now = now.Add(6 * time.Second)
+	budget.request(now)
+	statsAfterWindow := budget.Stats()
+
+	t.Logf("Should keep the counters for the window and drop them afterwards")
+	assert.False(t, afterFiveSeconds)
+	assert.Equal(t, BudgetStats{Requests: 1, Retries: 0, Exhausted: 1}, statsAfterWindow)
+}
+
+func TestRetryWithSharedBudget(t *testing.T) {
+	t.Logf("Given Budget allowing a single retry")
+	budget, _ := NewBudget(BudgetConfig{MinPerSecond: 0.1, Window: 10 * time.Second})
+
+	t.Logf("And given two Retry sharing it")
+	config := RetriesConfig{MaxRetries: 3, Delay: time.Millisecond, Factor: 1.0, Budget: budget}
+	first, _ := NewRetries(config)
+	second, _ := NewRetries(config)
+
+	t.Logf("And given a func which always fails")
+	var callCount int
+	expectedError := &RetryableError{}
+	funcToRetry := func() (*http.Response, error) {
+		callCount++
+		return nil, expectedError
+	}
+
+	t.Logf("When executing the func with both of them")
+	_, firstErr := first.Execute(funcToRetry)
+	_, secondErr := second.Execute(funcToRetry)
+
+	t.Logf("Should retry only once and return ExhaustedError with the budget as the reason")
+	assert.Equal(t, 3, callCount)
+	assert.Equal(t, &ExhaustedError{Attempts: []Attempt{{Number: 1, Err: expectedError}, {Number: 2, Err: expectedError}}, Reason: RetryBudgetExhaustedError}, firstErr)
+	assert.Equal(t, &ExhaustedError{Attempts: []Attempt{{Number: 1, Err: expectedError}}, Reason: RetryBudgetExhaustedError}, secondErr)
+	assert.True(t, errors.Is(secondErr, RetryBudgetExhaustedError))
+	assert.Equal(t, int64(1), first.Skipped())
+	assert.Equal(t, int64(1), second.Skipped())
+	assert.Equal(t, BudgetStats{Requests: 2, Retries: 1, Exhausted: 2}, budget.Stats())
+}
+
+func TestRetryWithBudgetOnManualClock(t *testing.T) {
+	t.Logf("Given Budget allowing a single retry within 2s window")
+	budget, _ := NewBudget(BudgetConfig{MinPerSecond: 0.5, Window: 2 * time.Second})
+
+	t.Logf("And given Retry using it with a manual clock")
+	manual := clock.NewManual(time.Unix(0, 0))
+	retry, _ := NewRetries(RetriesConfig{MaxRetries: 1, Delay: time.Millisecond, Factor: 1.0, Budget: budget, Clock: manual})
+
+	t.Logf("And given a func which fails once")
+	var callCount int
+	funcToRetry := func() (*http.Response, error) {
+		callCount++
+		if callCount == 1 {
+			return nil, &RetryableError{}
+		}
+		return &http.Response{}, nil
+	}
+	go func() {
+		manual.WaitForTimers(1)
+		manual.Advance(time.Millisecond)
+	}()
+
+	t.Logf("When executing the func and then advancing the clock by the window")
+	_, err := retry.Execute(funcToRetry)
+	statsWithinWindow := budget.Stats()
+	manual.Advance(2 * time.Second)
+	_, _ = retry.Execute(func() (*http.Response, error) { return &http.Response{}, nil })
+
+	t.Logf("Should count the window on the manual clock")
+	assert.NoError(t, err)
+	assert.Equal(t, BudgetStats{Requests: 1, Retries: 1}, statsWithinWindow)
+	assert.Equal(t, BudgetStats{Requests: 1}, budget.Stats())
+}
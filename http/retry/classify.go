@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"errors"
+	"net"
+)
+
+// Decides whether an attempt which failed with the error should be retried
+type Classifier func(err error) bool
+
+// Retries errors marked with RetryableError, the default of Do and the only classifier used by Execute
+func IfMarked(err error) bool {
+	var retryError *RetryableError
+	return errors.As(err, &retryError)
+}
+
+// Retries every error
+func IfAnyError(err error) bool {
+	return err != nil
+}
+
+// Retries network errors which are timeouts
+func IfTimeout(err error) bool {
+	var netError net.Error
+	return errors.As(err, &netError) && netError.Timeout()
+}
+
+// Retries errors matching any of the targets with errors.Is
+func IfErrorIs(targets ...error) Classifier {
+	return func(err error) bool {
+		for _, target := range targets {
+			if errors.Is(err, target) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Retries errors for which any of the classifiers returns true
+func AnyOf(classifiers ...Classifier) Classifier {
+	return func(err error) bool {
+		for _, classify := range classifiers {
+			if classify(err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Never retries errors for which the classifier returns true, e.g. Not(IfErrorIs(sql.ErrNoRows))
+func Not(classify Classifier) Classifier {
+	return func(err error) bool {
+		return !classify(err)
+	}
+}
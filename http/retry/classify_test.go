@@ -0,0 +1,122 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func TestClassifiers(t *testing.T) {
+	busyError := errors.New("database is busy")
+	testCases := []struct {
+		Name     string
+		Classify Classifier
+		Err      error
+		Expected bool
+	}{
+		{Name: "IfMarked", Classify: IfMarked, Err: &RetryableError{Err: busyError}, Expected: true},
+		{Name: "IfMarked", Classify: IfMarked, Err: busyError, Expected: false},
+		{Name: "IfAnyError", Classify: IfAnyError, Err: busyError, Expected: true},
+		{Name: "IfTimeout", Classify: IfTimeout, Err: &net.OpError{Op: "read", Err: timeoutError{}}, Expected: true},
+		{Name: "IfTimeout", Classify: IfTimeout, Err: busyError, Expected: false},
+		{Name: "IfErrorIs", Classify: IfErrorIs(context.DeadlineExceeded, busyError), Err: fmt.Errorf("query: %w", busyError), Expected: true},
+		{Name: "IfErrorIs", Classify: IfErrorIs(context.DeadlineExceeded), Err: busyError, Expected: false},
+		{Name: "AnyOf", Classify: AnyOf(IfTimeout, IfErrorIs(busyError)), Err: busyError, Expected: true},
+		{Name: "AnyOf", Classify: AnyOf(IfTimeout, IfMarked), Err: busyError, Expected: false},
+		{Name: "Not", Classify: Not(IfErrorIs(busyError)), Err: busyError, Expected: false},
+	}
+	for _, testCase := range testCases {
+		t.Logf("Given %s classifier and '%s' error", testCase.Name, testCase.Err)
+
+		t.Logf("When classifying the error")
+		retryable := testCase.Classify(testCase.Err)
+
+		t.Logf("Should return %t", testCase.Expected)
+		assert.Equal(t, testCase.Expected, retryable)
+	}
+}
+
+func TestDoWithResultOfAnyType(t *testing.T) {
+	t.Logf("Given Retry with OnRetry hook")
+	var retries []Attempt
+	retry, _ := NewRetries(RetriesConfig{
+		MaxRetries: 3,
+		Delay:      time.Millisecond,
+		Factor:     2.0,
+		OnRetry:    func(attempt Attempt) { retries = append(retries, attempt) },
+	})
+
+	t.Logf("And given a func returning rows which fails once with a busy database")
+	busyError := errors.New("database is busy")
+	var callCount int
+	query := func(ctx context.Context) ([]string, error) {
+		callCount++
+		if callCount == 1 {
+			return nil, busyError
+		}
+		return []string{"row"}, nil
+	}
+
+	t.Logf("When running the func with IfErrorIs classifier")
+	rows, err := Do(context.Background(), retry, IfErrorIs(busyError), query)
+
+	t.Logf("Should retry once and return the rows")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"row"}, rows)
+	assert.Equal(t, 2, callCount)
+	assert.Equal(t, []Attempt{{Number: 1, Err: busyError, Delay: time.Millisecond}}, retries)
+}
+
+func TestRunWithNonRetryableError(t *testing.T) {
+	t.Logf("Given Retry")
+	retry, _ := NewRetries(RetriesConfig{MaxRetries: 3, Delay: time.Millisecond, Factor: 1.0})
+
+	t.Logf("And given a func failing with an error which is not a timeout")
+	rejectedError := errors.New("message rejected")
+	var callCount int
+	consume := func(ctx context.Context) error {
+		callCount++
+		return rejectedError
+	}
+
+	t.Logf("When running the func with IfTimeout classifier")
+	err := Run(context.Background(), retry, IfTimeout, consume)
+
+	t.Logf("Should call only once and return the error")
+	assert.Equal(t, rejectedError, err)
+	assert.Equal(t, 1, callCount)
+}
+
+func TestRunWithExhaustedRetries(t *testing.T) {
+	t.Logf("Given Retry")
+	retry, _ := NewRetries(RetriesConfig{MaxRetries: 2, Delay: time.Millisecond, Factor: 1.0})
+
+	t.Logf("And given a func always failing")
+	unavailableError := errors.New("unavailable")
+	var callCount int
+	call := func(ctx context.Context) error {
+		callCount++
+		return unavailableError
+	}
+
+	t.Logf("When running the func with IfAnyError classifier")
+	err := Run(context.Background(), retry, IfAnyError, call)
+
+	t.Logf("Should call 3 times and return ExhaustedError wrapping the last error")
+	var exhaustedError *ExhaustedError
+	assert.True(t, errors.As(err, &exhaustedError))
+	assert.True(t, errors.Is(err, unavailableError))
+	assert.Equal(t, 3, callCount)
+}
@@ -1,6 +1,10 @@
 package retry
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // Errors returned during creation of the Retry by NewRetries
 var (
@@ -9,8 +13,12 @@ var (
 	FactorZeroError     = errors.New("factor has to be larger than 0")
 )
 
+// Returned by NewBudget when BudgetConfig has negative ratio or minimum
+var BudgetNegativeError = errors.New("budget ratio and minimum per second cannot be negative")
+
 // Returned by the caller within Retry.Execute whenever there's a need to do a retry.
-// Returning an error of a different type means there should be no retry
+// Returning an error of a different type means there should be no retry, funcs run with Do can pick
+// a Classifier instead, e.g. IfErrorIs or IfTimeout
 type RetryableError struct {
 	Err error
 }
@@ -25,3 +33,42 @@ func (e *RetryableError) Error() string {
 	}
 	return "retryable: " + e.Err.Error()
 }
+
+// Reasons of ExhaustedError when retrying stopped before MaxRetries were made
+var (
+	DeadlineTooCloseError     = errors.New("context deadline passes before the next retry")
+	RetryBudgetExhaustedError = errors.New("retry budget exhausted")
+)
+
+// Returned by Retry.Execute once it gives up on retryable errors, lists outcomes of every attempt
+type ExhaustedError struct {
+	Attempts []Attempt
+	// Why retrying stopped before all of the retries were made: DeadlineTooCloseError, RetryBudgetExhaustedError
+	// or the error of the context which was done while waiting, nil when all of the retries failed
+	Reason error
+}
+
+func (e *ExhaustedError) Error() string {
+	outcomes := make([]string, 0, len(e.Attempts))
+	for _, attempt := range e.Attempts {
+		outcomes = append(outcomes, attempt.String())
+	}
+	if e.Reason != nil {
+		return fmt.Sprintf("retries stopped after %d attempts as %s: %s", len(e.Attempts), e.Reason, strings.Join(outcomes, "; "))
+	}
+	return fmt.Sprintf("retries exhausted after %d attempts: %s", len(e.Attempts), strings.Join(outcomes, "; "))
+}
+
+// Returns error of the last attempt
+func (e *ExhaustedError) Unwrap() error {
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// Supports errors.Is against Reason on top of the error of the last attempt, e.g. context.Canceled
+func (e *ExhaustedError) Is(target error) bool {
+	return e.Reason != nil && errors.Is(e.Reason, target)
+}
+
+func (e *ExhaustedError) As(target interface{}) bool {
+	return e.Reason != nil && errors.As(e.Reason, target)
+}
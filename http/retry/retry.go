@@ -16,9 +16,14 @@
 package retry
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"math"
 	"net/http"
+	"strings"
+	"sync/atomic"
+	"test2/clock"
 	"time"
 )
 
@@ -26,6 +31,45 @@ type RetriesConfig struct {
 	MaxRetries int
 	Delay      time.Duration
 	Factor     float64
+	// Optional budget shared with other Retry instances, retries are skipped once it's exhausted
+	Budget *Budget
+	// Optional hooks, OnRetry is called before waiting for the next retry with its planned delay,
+	// OnGiveUp after the last failed attempt and OnSuccess after the successful one
+	OnRetry   func(Attempt)
+	OnGiveUp  func(Attempt)
+	OnSuccess func(Attempt)
+	// Optional clock used to wait between retries and to slide the Budget window, defaults to clock.System
+	Clock clock.Clock
+}
+
+// Outcome of a single run of RetryFunc passed to the hooks
+type Attempt struct {
+	// Starts from 1
+	Number int
+	// Status of the response, zero when there was none
+	StatusCode int
+	Err        error
+	// Delay before the next attempt, zero unless passed to OnRetry
+	Delay time.Duration
+}
+
+func (a Attempt) String() string {
+	var outcome []string
+	if a.StatusCode != 0 {
+		outcome = append(outcome, fmt.Sprintf("status %d", a.StatusCode))
+	}
+	cause := a.Err
+	var retryError *RetryableError
+	if errors.As(a.Err, &retryError) {
+		cause = retryError.Err
+	}
+	if cause != nil {
+		outcome = append(outcome, cause.Error())
+	}
+	if len(outcome) == 0 {
+		outcome = append(outcome, "failed")
+	}
+	return fmt.Sprintf("attempt %d: %s", a.Number, strings.Join(outcome, ", "))
 }
 
 // Constructs new Retry from RetriesConfig
@@ -43,12 +87,14 @@ func NewRetries(config RetriesConfig) (*Retry, error) {
 		return nil, FactorZeroError
 	}
 
+	config.Clock = clock.OrSystem(config.Clock)
 	return &Retry{config: config}, nil
 }
 
 // Constructed with NewRetry, contains Execute function for running HTTP requests with retries
 type Retry struct {
-	config RetriesConfig
+	config  RetriesConfig
+	skipped int64
 }
 
 type RetryFunc func() (*http.Response, error)
@@ -61,25 +107,122 @@ type RetryFunc func() (*http.Response, error)
 // The delay between retries is calculated based on a simple exponential-backoff equation: delay * factor^currentTry
 // Providing delay of 1 second, factor 2.0  and maximum number of retires will retry in 1s, 3s and 7s of delay between runs
 func (r *Retry) Execute(runnable RetryFunc) (*http.Response, error) {
+	return r.ExecuteWithContext(context.Background(), runnable)
+}
+
+// Works the same way as Execute, but stops retrying once ctx is done.
+//
+// Whenever it gives up on a retryable error the last response is returned along with ExhaustedError listing
+// every attempt, its Reason tells why retrying stopped early:
+// DeadlineTooCloseError when ctx has a deadline that would pass before the next retry starts,
+// ctx's error when ctx is done while waiting for the next retry and RetryBudgetExhaustedError
+// when RetriesConfig.Budget is exhausted. Reason is nil when all of the retries failed.
+//
+// Errors which are not retryable are returned as they are.
+func (r *Retry) ExecuteWithContext(ctx context.Context, runnable RetryFunc) (*http.Response, error) {
+	return Do(ctx, r, IfMarked, func(context.Context) (*http.Response, error) {
+		return runnable()
+	})
+}
+
+type maxRetriesKey struct{}
+
+// Returns a copy of ctx with which Do makes at most maxRetries retries instead of RetriesConfig.MaxRetries,
+// e.g. for calls already retried by the caller. Zero disables retries
+func WithMaxRetries(ctx context.Context, maxRetries int) context.Context {
+	return context.WithValue(ctx, maxRetriesKey{}, maxRetries)
+}
+
+func (r *Retry) maxRetries(ctx context.Context) int {
+	if maxRetries, ok := ctx.Value(maxRetriesKey{}).(int); ok {
+		return maxRetries
+	}
+	return r.config.MaxRetries
+}
+
+// Runs runnable with retries and returns its last result, works the same way as ExecuteWithContext
+// for any result type.
+//
+// Failed attempts are retried only when classify returns true for their error, IfMarked is used when it's nil.
+func Do[T any](ctx context.Context, r *Retry, classify Classifier, runnable func(context.Context) (T, error)) (T, error) {
+	if classify == nil {
+		classify = IfMarked
+	}
+	if r.config.Budget != nil {
+		r.config.Budget.request(r.config.Clock.Now())
+	}
+	maxRetries := r.maxRetries(ctx)
 	var tryCount int
+	var attempts []Attempt
 	for {
-		response, err := runnable()
-		if err == nil || tryCount >= r.config.MaxRetries {
-			return response, err
+		result, err := runnable(ctx)
+		attempt := Attempt{Number: tryCount + 1, StatusCode: statusCode(result), Err: err}
+		if err == nil {
+			call(r.config.OnSuccess, attempt)
+			return result, nil
 		}
+		attempts = append(attempts, attempt)
 
-		var retryError *RetryableError
-		if !errors.As(err, &retryError) {
-			return response, err
+		if !classify(err) {
+			call(r.config.OnGiveUp, attempt)
+			return result, err
+		}
+		if tryCount >= maxRetries {
+			call(r.config.OnGiveUp, attempt)
+			return result, &ExhaustedError{Attempts: attempts}
 		}
 
 		tryCount++
+		delay := r.next(tryCount)
+		if deadline, ok := ctx.Deadline(); ok && delay > time.Until(deadline) {
+			call(r.config.OnGiveUp, attempt)
+			return result, &ExhaustedError{Attempts: attempts, Reason: DeadlineTooCloseError}
+		}
+		if r.config.Budget != nil && !r.config.Budget.withdraw(r.config.Clock.Now()) {
+			atomic.AddInt64(&r.skipped, 1)
+			call(r.config.OnGiveUp, attempt)
+			return result, &ExhaustedError{Attempts: attempts, Reason: RetryBudgetExhaustedError}
+		}
+
+		attempt.Delay = delay
+		call(r.config.OnRetry, attempt)
+		timer := r.config.Clock.NewTimer(delay)
 		select {
-		case <-time.After(r.next(tryCount)):
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			attempt.Delay = 0
+			call(r.config.OnGiveUp, attempt)
+			return result, &ExhaustedError{Attempts: attempts, Reason: ctx.Err()}
 		}
+	}
+}
+
+// Runs runnable with retries, works the same way as Do for funcs without a result
+func Run(ctx context.Context, r *Retry, classify Classifier, runnable func(context.Context) error) error {
+	_, err := Do(ctx, r, classify, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, runnable(ctx)
+	})
+	return err
+}
 
+func call(hook func(Attempt), attempt Attempt) {
+	if hook != nil {
+		hook(attempt)
 	}
+}
+
+// Status of the result if it's an http response, zero otherwise
+func statusCode(result interface{}) int {
+	if response, ok := result.(*http.Response); ok && response != nil {
+		return response.StatusCode
+	}
+	return 0
+}
 
+// Returns the number of retries which were skipped as the budget was exhausted
+func (r *Retry) Skipped() int64 {
+	return atomic.LoadInt64(&r.skipped)
 }
 
 func (r *Retry) next(currentTry int) time.Duration {
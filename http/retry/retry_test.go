@@ -1,8 +1,11 @@
 package retry
 
 import (
+	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"test2/clock"
 	"testing"
 	"time"
 )
@@ -154,6 +157,29 @@ func TestRetryWithConstantFailures(t *testing.T) {
 	assert.Equal(t, &expectedResponse, response)
 }
 
+func TestRetryWithMaxRetriesFromContext(t *testing.T) {
+	t.Logf("Given valid RetriesConfig maxRetries=%d", 3)
+	retry, _ := NewRetries(RetriesConfig{MaxRetries: 3, Delay: time.Millisecond, Factor: 1.0})
+
+	t.Logf("And given a context limiting retries to %d", 1)
+	ctx := WithMaxRetries(context.Background(), 1)
+
+	t.Logf("And given a func failing constantly")
+	var callCount int
+	funcToRetry := func() (*http.Response, error) {
+		callCount++
+		return &http.Response{}, &RetryableError{}
+	}
+
+	t.Logf("When executing a func with the context")
+	_, err := retry.ExecuteWithContext(ctx, funcToRetry)
+
+	t.Logf("Should call function %d times and return ExhaustedError", 2)
+	assert.Equal(t, 2, callCount)
+	var exhausted *ExhaustedError
+	assert.True(t, errors.As(err, &exhausted))
+}
+
 func TestExponentialBackoff(t *testing.T) {
 	testCases := []struct {
 		MaxRetries    int
@@ -193,3 +219,174 @@ func TestExponentialBackoff(t *testing.T) {
 		assert.Equal(t, testCase.ExpectedDelay, delay)
 	}
 }
+
+func TestRetryWithDeadlineBeforeNextRetry(t *testing.T) {
+	config := RetriesConfig{MaxRetries: 3, Delay: time.Second, Factor: 2.0}
+	t.Logf("Given valid RetriesConfig maxRetries=%d delay=%s factor=%0.2f", config.MaxRetries, config.Delay, config.Factor)
+	t.Logf("And given Retry")
+	retry, _ := NewRetries(config)
+
+	t.Logf("And given a context expiring before the first retry")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	t.Logf("And given a func to run")
+	var callCount int
+	expectedResponse := http.Response{}
+	funcToRetry := func() (*http.Response, error) {
+		callCount++
+		return &expectedResponse, &RetryableError{}
+	}
+
+	t.Logf("When executing a func")
+	response, err := retry.ExecuteWithContext(ctx, funcToRetry)
+
+	t.Logf("Should call function once and return ExhaustedError with its error")
+	assert.Equal(t, 1, callCount)
+	assert.Equal(t, &ExhaustedError{Attempts: []Attempt{{Number: 1, Err: &RetryableError{}}}, Reason: DeadlineTooCloseError}, err)
+	assert.Equal(t, &expectedResponse, response)
+}
+
+func TestRetryWithContextCancelledWhileWaiting(t *testing.T) {
+	manual := clock.NewManual(time.Now())
+	config := RetriesConfig{MaxRetries: 3, Delay: time.Second, Factor: 2.0, Clock: manual}
+	t.Logf("Given valid RetriesConfig maxRetries=%d delay=%s factor=%0.2f and manual clock", config.MaxRetries, config.Delay, config.Factor)
+	t.Logf("And given Retry")
+	retry, _ := NewRetries(config)
+
+	t.Logf("And given a context cancelled once the retry waits for the next attempt")
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		manual.WaitForTimers(1)
+		cancel()
+	}()
+
+	t.Logf("And given a func to run")
+	var callCount int
+	funcToRetry := func() (*http.Response, error) {
+		callCount++
+		return nil, &RetryableError{}
+	}
+
+	t.Logf("When executing a func")
+	_, err := retry.ExecuteWithContext(ctx, funcToRetry)
+
+	t.Logf("Should call function once and return ExhaustedError matching context's error")
+	assert.Equal(t, 1, callCount)
+	var exhaustedError *ExhaustedError
+	assert.True(t, errors.As(err, &exhaustedError))
+	assert.Len(t, exhaustedError.Attempts, 1)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestExponentialBackoffWaitsOnClock(t *testing.T) {
+	start := time.Now()
+	manual := clock.NewManual(start)
+	config := RetriesConfig{MaxRetries: 3, Delay: time.Second, Factor: 2.0, Clock: manual}
+	t.Logf("Given valid RetriesConfig maxRetries=%d delay=%s factor=%0.2f and manual clock", config.MaxRetries, config.Delay, config.Factor)
+	t.Logf("And given Retry")
+	retry, _ := NewRetries(config)
+
+	t.Logf("And given a func recording the clock's time of every call")
+	var calls []time.Duration
+	funcToRetry := func() (*http.Response, error) {
+		calls = append(calls, manual.Now().Sub(start))
+		return nil, &RetryableError{}
+	}
+
+	t.Logf("When executing a func and advancing the clock whenever the retry waits")
+	done := make(chan error)
+	go func() {
+		_, err := retry.Execute(funcToRetry)
+		done <- err
+	}()
+	for _, delay := range []time.Duration{time.Second, 3 * time.Second, 7 * time.Second} {
+		manual.WaitForTimers(1)
+		manual.Advance(delay)
+	}
+	err := <-done
+
+	t.Logf("Should wait 1s, 3s and 7s between the calls without sleeping")
+	var exhaustedError *ExhaustedError
+	assert.True(t, errors.As(err, &exhaustedError))
+	assert.Equal(t, []time.Duration{0, time.Second, 4 * time.Second, 11 * time.Second}, calls)
+}
+
+func TestRetryWithHooks(t *testing.T) {
+	t.Logf("Given RetriesConfig with hooks")
+	var retries, successes, giveUps []Attempt
+	config := RetriesConfig{
+		MaxRetries: 3,
+		Delay:      time.Millisecond,
+		Factor:     2.0,
+		OnRetry:    func(attempt Attempt) { retries = append(retries, attempt) },
+		OnGiveUp:   func(attempt Attempt) { giveUps = append(giveUps, attempt) },
+		OnSuccess:  func(attempt Attempt) { successes = append(successes, attempt) },
+	}
+	retry, _ := NewRetries(config)
+
+	t.Logf("And given a func failing with 500 status, then with network error and then succeeding")
+	networkError := errors.New("connection refused")
+	results := []struct {
+		response *http.Response
+		err      error
+	}{
+		{response: &http.Response{StatusCode: 500}, err: &RetryableError{}},
+		{err: &RetryableError{Err: networkError}},
+		{response: &http.Response{StatusCode: 200}},
+	}
+	var callCount int
+	funcToRetry := func() (*http.Response, error) {
+		result := results[callCount]
+		callCount++
+		return result.response, result.err
+	}
+
+	t.Logf("When executing a func")
+	_, err := retry.Execute(funcToRetry)
+
+	t.Logf("Should report both of the retries with their delays and the success")
+	assert.NoError(t, err)
+	assert.Equal(t, []Attempt{
+		{Number: 1, StatusCode: 500, Err: &RetryableError{}, Delay: time.Millisecond},
+		{Number: 2, Err: &RetryableError{Err: networkError}, Delay: 3 * time.Millisecond},
+	}, retries)
+	assert.Equal(t, []Attempt{{Number: 3, StatusCode: 200}}, successes)
+	assert.Empty(t, giveUps)
+}
+
+func TestRetryWithExhaustedRetries(t *testing.T) {
+	t.Logf("Given RetriesConfig with OnGiveUp hook")
+	var giveUps []Attempt
+	config := RetriesConfig{
+		MaxRetries: 2,
+		Delay:      time.Millisecond,
+		Factor:     1.0,
+		OnGiveUp:   func(attempt Attempt) { giveUps = append(giveUps, attempt) },
+	}
+	retry, _ := NewRetries(config)
+
+	t.Logf("And given a func failing with network error and then with 503 status")
+	networkError := errors.New("connection refused")
+	var callCount int
+	funcToRetry := func() (*http.Response, error) {
+		callCount++
+		if callCount == 1 {
+			return nil, &RetryableError{Err: networkError}
+		}
+		return &http.Response{StatusCode: 503}, &RetryableError{}
+	}
+
+	t.Logf("When executing a func")
+	_, err := retry.Execute(funcToRetry)
+
+	t.Logf("Should return ExhaustedError listing every attempt")
+	var exhaustedError *ExhaustedError
+	assert.True(t, errors.As(err, &exhaustedError))
+	assert.Len(t, exhaustedError.Attempts, 3)
+	assert.EqualError(t, err, "retries exhausted after 3 attempts: attempt 1: connection refused; "+
+		"attempt 2: status 503; attempt 3: status 503")
+	var retryableError *RetryableError
+	assert.True(t, errors.As(err, &retryableError))
+	assert.Equal(t, []Attempt{{Number: 3, StatusCode: 503, Err: &RetryableError{}}}, giveUps)
+}
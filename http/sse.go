@@ -0,0 +1,308 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	corehttp "net/http"
+	"strconv"
+	"strings"
+	"test2/http/retry"
+	"time"
+)
+
+const (
+	defaultStreamBuffer         = 16
+	defaultStreamMaxEventSize   = 1 << 20
+	defaultStreamReconnectDelay = 3 * time.Second
+	eventStreamMediaType        = "text/event-stream"
+)
+
+// Single server-sent event
+type Event struct {
+	// Last id sent by the server, it's kept by the events which don't set their own one
+	Id string
+	// Type of the event, message unless the server sets it
+	Event string
+	// Data lines joined with \n
+	Data string
+	// Reconnection delay requested by the server along with the event, zero when it's not set
+	Retry time.Duration
+}
+
+type StreamOptions struct {
+	// Optional id of the last event seen by the caller, sent as Last-Event-ID with the first connection
+	LastEventId string
+	// Events buffered for a slow consumer, the stream isn't read while the buffer is full. Defaults to 16
+	Buffer int
+	// Events larger than that drop the connection, defaults to 1MB
+	MaxEventSize int
+	// Delay before reconnecting a dropped connection unless the server sets one with retry, defaults to 3s
+	ReconnectDelay time.Duration
+	// Optional, called with every error which drops the connection
+	OnError func(error)
+}
+
+// Streams server-sent events from url until ctx is done, the channel is closed afterwards.
+//
+// Connections are retried with the backoff of ClientConfig.Retries, the first connection is made before returning
+// and its error (e.g. ClientHttpError or UnsupportedMediaTypeError when the url is not an event stream) is returned.
+//
+// Dropped connections are reconnected after the delay set by the server (or StreamOptions.ReconnectDelay)
+// with Last-Event-ID of the last event. The channel is closed once reconnecting fails, the server responds with
+// 204 status or ctx is done.
+//
+// If any of StreamOptions is negative it returns StreamOptionsNegativeError.
+func (c *Client) Stream(ctx context.Context, url string, options StreamOptions) (<-chan Event, error) {
+	if options.Buffer < 0 || options.MaxEventSize < 0 || options.ReconnectDelay < 0 {
+		return nil, StreamOptionsNegativeError
+	}
+	if options.Buffer == 0 {
+		options.Buffer = defaultStreamBuffer
+	}
+	if options.MaxEventSize == 0 {
+		options.MaxEventSize = defaultStreamMaxEventSize
+	}
+	if options.ReconnectDelay == 0 {
+		options.ReconnectDelay = defaultStreamReconnectDelay
+	}
+
+	// Streams are read for as long as they last, the attempt timeout only covers waiting for the headers
+	streamClient := *c.client
+	streamClient.Timeout = 0
+	s := &stream{
+		client:         c,
+		streamClient:   &streamClient,
+		url:            url,
+		options:        options,
+		lastEventId:    options.LastEventId,
+		reconnectDelay: options.ReconnectDelay,
+		events:         make(chan Event, options.Buffer),
+	}
+	body, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	go s.run(ctx, body)
+	return s.events, nil
+}
+
+type stream struct {
+	client         *Client
+	streamClient   *corehttp.Client
+	url            string
+	options        StreamOptions
+	lastEventId    string
+	reconnectDelay time.Duration
+	events         chan Event
+}
+
+func (s *stream) run(ctx context.Context, body io.ReadCloser) {
+	defer close(s.events)
+	for {
+		err := s.read(ctx, body)
+		body.Close()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.reportError(err)
+		}
+
+		select {
+		case <-s.client.clock.After(s.reconnectDelay):
+		case <-ctx.Done():
+			return
+		}
+		body, err = s.connect(ctx)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, StreamEndedError) {
+				s.reportError(err)
+			}
+			return
+		}
+	}
+}
+
+func (s *stream) connect(ctx context.Context) (io.ReadCloser, error) {
+	body, err := retry.Do(ctx, s.client.retry, nil, s.open)
+	s.client.metrics.retried(err)
+	return body, err
+}
+
+// Opens a single connection, failures worth retrying are returned as RetryableError
+func (s *stream) open(ctx context.Context) (io.ReadCloser, error) {
+	headers := Headers{"Accept": eventStreamMediaType, "Cache-Control": "no-cache"}
+	if s.lastEventId != "" {
+		headers["Last-Event-ID"] = s.lastEventId
+	}
+	connCtx, cancel := context.WithCancel(ctx)
+	request, err := s.client.createRequest(WithHeaders(connCtx, headers), corehttp.MethodGet, s.url, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// Compressed streams can't be decoded event by event
+	request.Header.Del("Accept-Encoding")
+	if s.client.endpoints != nil {
+		if _, err := s.client.endpoints.resolve(request, nil); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	url := request.URL.String()
+
+	s.client.logNewRequest(request.Method, url, routeOf(ctx))
+	stopTimer := s.cancelAfter(s.client.timeouts[AttemptBudget], cancel)
+	response, err := s.streamClient.Do(request)
+	stopTimer()
+	if err != nil {
+		cancel()
+		return nil, &retry.RetryableError{Err: &ClientError{Message: "network error", Url: url, Err: err}}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	switch {
+	case response.StatusCode == corehttp.StatusNoContent:
+		err = StreamEndedError
+	case response.StatusCode >= 500 || response.StatusCode == corehttp.StatusTooManyRequests:
+		err = &retry.RetryableError{Err: &ClientHttpError{Url: url, StatusCode: response.StatusCode}}
+	case response.StatusCode >= 400:
+		err = &ClientHttpError{Url: url, StatusCode: response.StatusCode}
+	case mediaType != eventStreamMediaType:
+		err = &UnsupportedMediaTypeError{Url: url, ContentType: response.Header.Get("Content-Type")}
+	}
+	if err != nil {
+		closeResponse(response)
+		cancel()
+		return nil, err
+	}
+	return &cancelOnClose{ReadCloser: response.Body, cancel: cancel}, nil
+}
+
+// Calls cancel once timeout passes on the client's clock unless the returned func is called before,
+// zero timeout never cancels
+func (s *stream) cancelAfter(timeout time.Duration, cancel context.CancelFunc) func() {
+	if timeout <= 0 {
+		return func() {}
+	}
+	timer := s.client.clock.NewTimer(timeout)
+	stopped := make(chan struct{})
+	go func() {
+		select {
+		case <-timer.C():
+			cancel()
+		case <-stopped:
+		}
+	}()
+	return func() {
+		timer.Stop()
+		close(stopped)
+	}
+}
+
+// Reads events until the body ends, blocks while the buffer of events is full
+func (s *stream) read(ctx context.Context, body io.Reader) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), s.options.MaxEventSize)
+	scanner.Split(scanLines)
+
+	var data bytes.Buffer
+	var event Event
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\uFEFF")
+			first = false
+		}
+		if line == "" {
+			if data.Len() > 0 {
+				event.Id = s.lastEventId
+				event.Data = strings.TrimSuffix(data.String(), "\n")
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				select {
+				case s.events <- event:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			data.Reset()
+			event = Event{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if index := strings.IndexByte(line, ':'); index >= 0 {
+			field, value = line[:index], strings.TrimPrefix(line[index+1:], " ")
+		}
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			if data.Len()+len(value) >= s.options.MaxEventSize {
+				return &ClientError{Message: "stream error", Url: s.url, Err: EventTooLargeError}
+			}
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastEventId = value
+			}
+		case "retry":
+			if milliseconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+				event.Retry = time.Duration(milliseconds) * time.Millisecond
+				s.reconnectDelay = event.Retry
+			}
+		}
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return &ClientError{Message: "stream error", Url: s.url, Err: EventTooLargeError}
+	}
+	if err := scanner.Err(); err != nil {
+		return &ClientError{Message: "stream error", Url: s.url, Err: err}
+	}
+	return nil
+}
+
+func (s *stream) reportError(err error) {
+	if s.options.OnError != nil {
+		s.options.OnError(err)
+	}
+	if s.client.logging {
+		log.Printf("Stream from [%s] dropped: %s \n", s.url, err)
+	}
+}
+
+// Splits lines ended with \n, \r\n or a lone \r as the event stream format allows all of them
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	for i, b := range data {
+		switch b {
+		case '\n':
+			return i + 1, data[:i], nil
+		case '\r':
+			if i+1 == len(data) && !atEOF {
+				// \r\n may be split between reads
+				return 0, nil, nil
+			}
+			if i+1 < len(data) && data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+	}
+	if atEOF && len(data) > 0 {
+		// Events are only dispatched on a blank line, so an incomplete last line is dropped with its event
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
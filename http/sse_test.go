@@ -0,0 +1,248 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"test2/clock"
+	"test2/http/retry"
+	"testing"
+	"time"
+)
+
+func TestClient_StreamParsesEvents(t *testing.T) {
+	t.Logf("Given SSE server sending events with every field, comments and CRLF line endings")
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		accept = req.Header.Get("Accept")
+		res.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		fmt.Fprint(res, ": keep-alive\r\n\r\n")
+		fmt.Fprint(res, "id: 1\r\ndata: first\r\n\r\n")
+		fmt.Fprint(res, "event: item.updated\nretry: 50\ndata: line 1\ndata:line 2\n\n")
+		fmt.Fprint(res, "data\n\n")
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When streaming events")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.Stream(ctx, server.URL, StreamOptions{})
+
+	t.Logf("Should parse every event, keeping the last id")
+	assert.NoError(t, err)
+	assert.Equal(t, "text/event-stream", accept)
+	assert.Equal(t, Event{Id: "1", Event: "message", Data: "first"}, <-events)
+	assert.Equal(t, Event{Id: "1", Event: "item.updated", Data: "line 1\nline 2", Retry: 50 * time.Millisecond}, <-events)
+	assert.Equal(t, Event{Id: "1", Event: "message", Data: ""}, <-events)
+}
+
+func TestClient_StreamReconnectsWithLastEventId(t *testing.T) {
+	t.Logf("Given SSE server closing the first connection after 2 events and failing the second one")
+	var mutex sync.Mutex
+	var lastEventIds []string
+	server := httptest.NewServer(sseHandler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		lastEventIds = append(lastEventIds, req.Header.Get("Last-Event-ID"))
+		connection := len(lastEventIds)
+		mutex.Unlock()
+		switch connection {
+		case 1:
+			fmt.Fprint(res, "retry: 1\n\nid: 1\ndata: a\n\nid: 2\ndata: b\n\n")
+		case 2:
+			res.WriteHeader(503)
+		default:
+			fmt.Fprint(res, "id: 3\ndata: c\n\n")
+		}
+	})))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When streaming events")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.Stream(ctx, server.URL, StreamOptions{LastEventId: "0"})
+
+	t.Logf("Should reconnect with the id of the last event after retrying the failed connection")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", (<-events).Data)
+	assert.Equal(t, "b", (<-events).Data)
+	assert.Equal(t, Event{Id: "3", Event: "message", Data: "c"}, <-events)
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []string{"0", "2", "2"}, lastEventIds)
+}
+
+func TestClient_StreamWithoutEventStream(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Handler       http.HandlerFunc
+		ExpectedError func(err error) bool
+	}{
+		{Name: "JSON response", Handler: func(res http.ResponseWriter, req *http.Request) {
+			res.Header().Set("Content-Type", "application/json")
+			res.Write([]byte(`[]`))
+		}, ExpectedError: func(err error) bool {
+			var mediaTypeError *UnsupportedMediaTypeError
+			return errors.As(err, &mediaTypeError)
+		}},
+		{Name: "404 status", Handler: func(res http.ResponseWriter, req *http.Request) {
+			res.WriteHeader(404)
+		}, ExpectedError: func(err error) bool {
+			var httpError *ClientHttpError
+			return errors.As(err, &httpError) && httpError.StatusCode == 404
+		}},
+		{Name: "204 status", Handler: func(res http.ResponseWriter, req *http.Request) {
+			res.WriteHeader(204)
+		}, ExpectedError: func(err error) bool {
+			return errors.Is(err, StreamEndedError)
+		}},
+	}
+	for _, testCase := range testCases {
+		t.Logf("Given HTTP server returning %s", testCase.Name)
+		server := httptest.NewServer(testCase.Handler)
+
+		t.Logf("And given Client")
+		client, _ := NewClient(validClientConfig)
+
+		t.Logf("When streaming events")
+		events, err := client.Stream(context.Background(), server.URL, StreamOptions{})
+
+		t.Logf("Should return the error of the first connection")
+		assert.Nil(t, events)
+		assert.True(t, testCase.ExpectedError(err), "unexpected error %v", err)
+		server.Close()
+	}
+}
+
+func TestClient_StreamWithHeaderTimeoutOnClock(t *testing.T) {
+	t.Logf("Given SSE server never sending the headers")
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client with a manual clock")
+	manual := clock.NewManual(time.Now())
+	config := validClientConfig
+	config.Timeout = time.Minute
+	config.Clock = manual
+	client, _ := NewClient(config)
+
+	t.Logf("When streaming events and advancing the clock by the timeout")
+	go func() {
+		manual.WaitForTimers(1)
+		manual.Advance(time.Minute)
+	}()
+	events, err := client.Stream(retry.WithMaxRetries(context.Background(), 0), server.URL, StreamOptions{})
+
+	t.Logf("Should drop the connection without waiting for the timeout in real time")
+	assert.Nil(t, events)
+	assert.Error(t, err)
+}
+
+func TestClient_StreamWithSlowConsumer(t *testing.T) {
+	t.Logf("Given SSE server sending 1000 events")
+	server := httptest.NewServer(sseHandler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		for i := 0; i < 1000; i++ {
+			fmt.Fprintf(res, "id: %d\ndata: event\n\n", i)
+		}
+	})))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When streaming events with buffer of 2 and not reading them")
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := client.Stream(ctx, server.URL, StreamOptions{Buffer: 2})
+	assert.NoError(t, err)
+	for len(events) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Logf("Should buffer only 2 events and close the channel once ctx is cancelled")
+	assert.Equal(t, 2, cap(events))
+	cancel()
+	var received int
+	for range events {
+		received++
+	}
+	assert.LessOrEqual(t, received, 3)
+}
+
+func TestClient_StreamWithTooLargeEvent(t *testing.T) {
+	t.Logf("Given SSE server sending event larger than the limit and closing the stream with 204 afterwards")
+	var connections int
+	server := httptest.NewServer(sseHandler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		connections++
+		if connections > 1 {
+			res.WriteHeader(204)
+			return
+		}
+		fmt.Fprintf(res, "retry: 1\ndata: %s\n\n", strings.Repeat("a", 100))
+	})))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When streaming events of at most 64 bytes")
+	var errs []error
+	events, err := client.Stream(context.Background(), server.URL, StreamOptions{
+		MaxEventSize: 64,
+		OnError:      func(err error) { errs = append(errs, err) },
+	})
+	assert.NoError(t, err)
+
+	t.Logf("Should drop the connection with EventTooLargeError and end the stream")
+	for range events {
+		t.Errorf("unexpected event")
+	}
+	assert.Len(t, errs, 1)
+	assert.True(t, errors.Is(errs[0], EventTooLargeError))
+}
+
+func TestScanLines(t *testing.T) {
+	testCases := []struct {
+		Data          string
+		AtEOF         bool
+		ExpectedLine  string
+		ExpectedCount int
+	}{
+		{Data: "a\nb", ExpectedLine: "a", ExpectedCount: 2},
+		{Data: "a\r\nb", ExpectedLine: "a", ExpectedCount: 3},
+		{Data: "a\rb", ExpectedLine: "a", ExpectedCount: 2},
+		{Data: "a\r", ExpectedLine: "", ExpectedCount: 0},
+		{Data: "a\r", AtEOF: true, ExpectedLine: "a", ExpectedCount: 2},
+		{Data: "a", AtEOF: true, ExpectedLine: "a", ExpectedCount: 1},
+	}
+	for _, testCase := range testCases {
+		t.Logf("Given data %q atEOF=%t", testCase.Data, testCase.AtEOF)
+
+		t.Logf("When scanning a line")
+		count, line, err := scanLines([]byte(testCase.Data), testCase.AtEOF)
+
+		t.Logf("Should return line %q after %d bytes", testCase.ExpectedLine, testCase.ExpectedCount)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.ExpectedLine, string(line))
+		assert.Equal(t, testCase.ExpectedCount, count)
+	}
+}
+
+// Sets the event stream Content-Type, handlers can override the status afterwards
+func sseHandler(handler http.Handler) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "text/event-stream")
+		handler.ServeHTTP(res, req)
+	}
+}
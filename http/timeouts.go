@@ -0,0 +1,144 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	corehttp "net/http"
+	"strings"
+	"time"
+)
+
+// Names one of the timeouts that can expire while the Client runs a request
+type TimeoutBudget string
+
+const (
+	DialBudget           TimeoutBudget = "dial"
+	TLSHandshakeBudget   TimeoutBudget = "tls handshake"
+	ResponseHeaderBudget TimeoutBudget = "response header"
+	AttemptBudget        TimeoutBudget = "attempt"
+	TotalBudget          TimeoutBudget = "total"
+)
+
+const (
+	defaultDialTimeout = 30 * time.Second
+	defaultKeepAlive   = 30 * time.Second
+)
+
+// Returned by the dialer created with newDialContext, makes dial timeouts distinguishable from other timeouts
+type dialTimeoutError struct {
+	err error
+}
+
+func (e *dialTimeoutError) Error() string {
+	return e.err.Error()
+}
+
+func (e *dialTimeoutError) Unwrap() error {
+	return e.err
+}
+
+func (e *dialTimeoutError) Timeout() bool {
+	return true
+}
+
+func (e *dialTimeoutError) Temporary() bool {
+	return true
+}
+
+func newDialContext(timeout time.Duration) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: defaultKeepAlive}
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		var netError net.Error
+		if err != nil && ctx.Err() == nil && errors.As(err, &netError) && netError.Timeout() {
+			return nil, &dialTimeoutError{err: err}
+		}
+		return conn, err
+	}
+}
+
+func newTimeouts(config ClientConfig, client *corehttp.Client) map[TimeoutBudget]time.Duration {
+	timeouts := map[TimeoutBudget]time.Duration{
+		AttemptBudget: client.Timeout,
+		TotalBudget:   config.TotalTimeout,
+	}
+	if transport, ok := client.Transport.(*corehttp.Transport); ok {
+		timeouts[TLSHandshakeBudget] = transport.TLSHandshakeTimeout
+		timeouts[ResponseHeaderBudget] = transport.ResponseHeaderTimeout
+	}
+	if config.HttpClient == nil && config.RoundTripper == nil {
+		timeouts[DialBudget] = dialTimeout(config.Transport)
+	}
+	return timeouts
+}
+
+func dialTimeout(config TransportConfig) time.Duration {
+	if config.DialTimeout > 0 {
+		return config.DialTimeout
+	}
+	return defaultDialTimeout
+}
+
+type extraTimeoutKey struct{}
+
+// Returns a copy of ctx with which requests get extra time on top of the attempt and total timeouts,
+// e.g. long polls which the server holds for a while. Such requests are never hedged.
+// The response header timeout of the transport is not extended
+func WithExtraTimeout(ctx context.Context, extra time.Duration) context.Context {
+	return context.WithValue(ctx, extraTimeoutKey{}, extra)
+}
+
+func extraTimeout(ctx context.Context) time.Duration {
+	extra, _ := ctx.Value(extraTimeoutKey{}).(time.Duration)
+	return extra
+}
+
+// Limit of the budget for requests sent with ctx, zero when it's disabled
+func (c *Client) timeout(ctx context.Context, budget TimeoutBudget) time.Duration {
+	limit := c.timeouts[budget]
+	if limit > 0 && (budget == AttemptBudget || budget == TotalBudget) {
+		limit += extraTimeout(ctx)
+	}
+	return limit
+}
+
+func (c *Client) withTotalTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeouts[TotalBudget] <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout(ctx, TotalBudget))
+}
+
+// Converts err into TimeoutError when one of the Client's budgets expired,
+// errors caused by caller's own context are returned as they are
+func (c *Client) timeoutError(ctx context.Context, operationCtx context.Context, url string, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	if operationCtx.Err() != nil {
+		return &TimeoutError{Url: url, Budget: TotalBudget, Limit: c.timeout(ctx, TotalBudget), Err: err}
+	}
+
+	var timeout interface{ Timeout() bool }
+	if !errors.As(err, &timeout) || !timeout.Timeout() {
+		return err
+	}
+	budget := budgetOf(err)
+	return &TimeoutError{Url: url, Budget: budget, Limit: c.timeout(ctx, budget), Err: err}
+}
+
+// The transport does not export its timeout errors, those are recognized by their messages
+func budgetOf(err error) TimeoutBudget {
+	var dialError *dialTimeoutError
+	switch {
+	case errors.As(err, &dialError):
+		return DialBudget
+	case strings.Contains(err.Error(), "TLS handshake timeout"):
+		return TLSHandshakeBudget
+	case strings.Contains(err.Error(), "timeout awaiting response headers"):
+		return ResponseHeaderBudget
+	default:
+		return AttemptBudget
+	}
+}
@@ -0,0 +1,190 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"test2/http/retry"
+	"testing"
+	"time"
+)
+
+func TestClient_GetWithTimeouts(t *testing.T) {
+	t.Logf("Given HTTP server responding after 300ms")
+	server := httptest.NewServer(slowRequestHandler(300 * time.Millisecond))
+	defer server.Close()
+
+	testCases := []struct {
+		Name           string
+		Timeout        time.Duration
+		TotalTimeout   time.Duration
+		Transport      TransportConfig
+		ExpectedBudget TimeoutBudget
+		ExpectedLimit  time.Duration
+	}{
+		{Name: "attempt", Timeout: 50 * time.Millisecond, ExpectedBudget: AttemptBudget, ExpectedLimit: 50 * time.Millisecond},
+		{Name: "response header", Timeout: time.Second, Transport: TransportConfig{ResponseHeaderTimeout: 50 * time.Millisecond},
+			ExpectedBudget: ResponseHeaderBudget, ExpectedLimit: 50 * time.Millisecond},
+		{Name: "total", Timeout: time.Second, TotalTimeout: 100 * time.Millisecond, ExpectedBudget: TotalBudget, ExpectedLimit: 100 * time.Millisecond},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("And given Client with %s timeout", testCase.Name)
+		client, _ := NewClient(ClientConfig{
+			Timeout:      testCase.Timeout,
+			TotalTimeout: testCase.TotalTimeout,
+			Transport:    testCase.Transport,
+			Retries:      retry.RetriesConfig{MaxRetries: 1, Delay: time.Millisecond, Factor: 2},
+		})
+
+		t.Logf("When calling GET")
+		var dummyResponse DummyResponse
+		err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+		t.Logf("Should return TimeoutError with %s budget", testCase.ExpectedBudget)
+		var timeoutError *TimeoutError
+		assert.True(t, errors.As(err, &timeoutError))
+		assert.Equal(t, testCase.ExpectedBudget, timeoutError.Budget)
+		assert.Equal(t, testCase.ExpectedLimit, timeoutError.Limit)
+		assert.Equal(t, server.URL, timeoutError.Url)
+	}
+}
+
+func TestClient_GetWithExtraTimeout(t *testing.T) {
+	t.Logf("Given HTTP server responding after 300ms")
+	server := httptest.NewServer(slowRequestHandler(300 * time.Millisecond))
+	defer server.Close()
+
+	t.Logf("And given Client with 50ms timeout and 100ms total timeout")
+	client, _ := NewClient(ClientConfig{
+		Timeout:      50 * time.Millisecond,
+		TotalTimeout: 100 * time.Millisecond,
+		Retries:      retry.RetriesConfig{MaxRetries: 1, Delay: time.Millisecond, Factor: 2},
+	})
+
+	t.Logf("When calling GET with 1s of extra time")
+	var dummyResponse DummyResponse
+	err := client.Get(WithExtraTimeout(context.Background(), time.Second), server.URL, &dummyResponse)
+
+	t.Logf("Should wait for the response")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, dummyResponse.Id)
+}
+
+func TestClient_GetWithTLSHandshakeTimeout(t *testing.T) {
+	t.Logf("Given TCP server which never completes TLS handshake")
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer listener.Close()
+	go func() {
+		var connections []net.Conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				for _, connection := range connections {
+					connection.Close()
+				}
+				return
+			}
+			connections = append(connections, conn)
+		}
+	}()
+
+	t.Logf("And given Client with TLS handshake timeout")
+	client, _ := NewClient(ClientConfig{
+		Timeout:   time.Second,
+		Transport: TransportConfig{TLSHandshakeTimeout: 50 * time.Millisecond},
+		Retries:   retry.RetriesConfig{MaxRetries: 1, Delay: time.Millisecond, Factor: 2},
+	})
+
+	t.Logf("When calling GET")
+	var dummyResponse DummyResponse
+	err := client.Get(context.Background(), fmt.Sprintf("https://%s", listener.Addr()), &dummyResponse)
+
+	t.Logf("Should return TimeoutError with tls handshake budget")
+	var timeoutError *TimeoutError
+	assert.True(t, errors.As(err, &timeoutError))
+	assert.Equal(t, TLSHandshakeBudget, timeoutError.Budget)
+	assert.Equal(t, 50*time.Millisecond, timeoutError.Limit)
+}
+
+func TestClient_GetWithTotalTimeoutStoppingRetries(t *testing.T) {
+	t.Logf("Given HTTP server returning 503 status")
+	callCount := make(map[string]int)
+	server := httptest.NewServer(requestHandler(503, &callCount))
+	defer server.Close()
+
+	t.Logf("And given Client which total timeout is shorter than delay between retries")
+	client, _ := NewClient(ClientConfig{
+		Timeout:      time.Second,
+		TotalTimeout: 100 * time.Millisecond,
+		Retries:      retry.RetriesConfig{MaxRetries: 3, Delay: time.Second, Factor: 2},
+	})
+
+	t.Logf("When calling GET")
+	var dummyResponse DummyResponse
+	startTime := time.Now()
+	err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+	t.Logf("Should not retry and return ClientHttpError")
+	assert.EqualError(t, err, (&ClientHttpError{Url: server.URL, StatusCode: 503}).Error())
+	assert.Equal(t, 1, callCount["/"])
+	assert.True(t, time.Now().Sub(startTime) < time.Second)
+}
+
+func TestClient_GetWithCallerContextTimeout(t *testing.T) {
+	t.Logf("Given HTTP server responding after 300ms")
+	server := httptest.NewServer(slowRequestHandler(300 * time.Millisecond))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling GET with context expiring after 50ms")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var dummyResponse DummyResponse
+	err := client.Get(ctx, server.URL, &dummyResponse)
+
+	t.Logf("Should return ClientError as no budget of the Client expired")
+	var timeoutError *TimeoutError
+	var clientError *ClientError
+	assert.False(t, errors.As(err, &timeoutError))
+	assert.True(t, errors.As(err, &clientError))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestBudgetOf(t *testing.T) {
+	testCases := []struct {
+		Err            error
+		ExpectedBudget TimeoutBudget
+	}{
+		{Err: &ClientError{Err: &dialTimeoutError{err: errors.New("i/o timeout")}}, ExpectedBudget: DialBudget},
+		{Err: errors.New("net/http: TLS handshake timeout"), ExpectedBudget: TLSHandshakeBudget},
+		{Err: errors.New("net/http: timeout awaiting response headers"), ExpectedBudget: ResponseHeaderBudget},
+		{Err: context.DeadlineExceeded, ExpectedBudget: AttemptBudget},
+	}
+
+	for _, testCase := range testCases {
+		t.Logf("Given error '%s'", testCase.Err)
+
+		t.Logf("When recognizing budget")
+		budget := budgetOf(testCase.Err)
+
+		t.Logf("Should return %s budget", testCase.ExpectedBudget)
+		assert.Equal(t, testCase.ExpectedBudget, budget)
+	}
+}
+
+func slowRequestHandler(delay time.Duration) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		select {
+		case <-time.After(delay):
+			res.Write([]byte(`{"id":1,"title":"Jan"}`))
+		case <-req.Context().Done():
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	corehttp "net/http"
+	"net/url"
+	"time"
+)
+
+// Configures the transport layer used by the Client: connection pooling, proxies, TLS and HTTP/2.
+//
+// All of the fields are optional, zero values keep the defaults of go's http.DefaultTransport.
+type TransportConfig struct {
+	// Timeouts of the connection phases, DialTimeout defaults to 30s, TLSHandshakeTimeout to 10s
+	// and ResponseHeaderTimeout is disabled by default
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+
+	// Connection pooling
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	DisableKeepAlives   bool
+
+	// Proxy used for every outgoing request, if nil proxy is read from HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// environment variables unless DisableProxy is set
+	Proxy        *url.URL
+	DisableProxy bool
+
+	// Certificate authorities used to verify the server, RootCAs takes precedence over CACertPEM and CACertFile.
+	// When none of those are set, system's pool is used
+	RootCAs    *x509.CertPool
+	CACertPEM  []byte
+	CACertFile string
+
+	// Client certificates presented to the server (mTLS), either provided directly or loaded from PEM files
+	ClientCertificates []tls.Certificate
+	ClientCertFile     string
+	ClientKeyFile      string
+
+	ServerName         string
+	MinTLSVersion      uint16
+	InsecureSkipVerify bool
+
+	// HTTP/2 is negotiated over TLS by default, DisableHTTP2 forces HTTP/1.1
+	DisableHTTP2 bool
+}
+
+// Creates a new http.Transport based on go's http.DefaultTransport with TransportConfig applied on top of it.
+//
+// If a CA bundle does not contain any valid certificate it returns InvalidCACertError.
+//
+// If any of the certificate files cannot be read or parsed, the underlying error is returned.
+func NewTransport(config TransportConfig) (*corehttp.Transport, error) {
+	transport := corehttp.DefaultTransport.(*corehttp.Transport).Clone()
+	transport.DialContext = newDialContext(dialTimeout(config))
+
+	if config.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	}
+	if config.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+	}
+
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+	transport.DisableKeepAlives = config.DisableKeepAlives
+
+	if config.Proxy != nil {
+		transport.Proxy = corehttp.ProxyURL(config.Proxy)
+	} else if config.DisableProxy {
+		transport.Proxy = nil
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if config.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) corehttp.RoundTripper{}
+	}
+	return transport, nil
+}
+
+func newTLSConfig(config TransportConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		MinVersion:         config.MinTLSVersion,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	rootCAs, err := loadRootCAs(config)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.RootCAs = rootCAs
+
+	tlsConfig.Certificates = append(tlsConfig.Certificates, config.ClientCertificates...)
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, certificate)
+	}
+	return tlsConfig, nil
+}
+
+func loadRootCAs(config TransportConfig) (*x509.CertPool, error) {
+	if config.RootCAs != nil {
+		return config.RootCAs, nil
+	}
+
+	bundle := config.CACertPEM
+	if len(bundle) == 0 && config.CACertFile != "" {
+		content, err := ioutil.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		bundle = content
+	}
+	if len(bundle) == 0 {
+		return nil, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, InvalidCACertError
+	}
+	return pool, nil
+}
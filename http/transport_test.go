@@ -0,0 +1,277 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestNewTransportWithInvalidCABundle(t *testing.T) {
+	t.Logf("Given TransportConfig with invalid CA bundle")
+	config := TransportConfig{CACertPEM: []byte("not a certificate")}
+
+	t.Logf("When creating Transport")
+	transport, err := NewTransport(config)
+
+	t.Logf("Should return InvalidCACertError")
+	assert.Equal(t, InvalidCACertError, err)
+	assert.Nil(t, transport)
+}
+
+func TestNewTransportWithPoolingSettings(t *testing.T) {
+	t.Logf("Given TransportConfig with connection pooling settings")
+	config := TransportConfig{
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 5,
+		MaxConnsPerHost:     20,
+		IdleConnTimeout:     time.Minute,
+	}
+
+	t.Logf("When creating Transport")
+	transport, err := NewTransport(config)
+
+	t.Logf("Should apply all the settings")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, transport.MaxIdleConns)
+	assert.Equal(t, 5, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 20, transport.MaxConnsPerHost)
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+}
+
+func TestClient_GetOverTLS(t *testing.T) {
+	t.Logf("Given HTTPS server returning 200 status")
+	callCount := make(map[string]int)
+	server := httptest.NewTLSServer(requestHandlerWithBody(200, &callCount, DummyResponse{Title: "Jan", Id: 1}))
+	defer server.Close()
+
+	testCases := []struct {
+		Name      string
+		Transport TransportConfig
+	}{
+		{Name: "RootCAs", Transport: TransportConfig{RootCAs: certPool(server.Certificate())}},
+		{Name: "CACertPEM", Transport: TransportConfig{CACertPEM: encodeCertificate(server.Certificate())}},
+	}
+
+	for _, testCase := range testCases {
+		config := validClientConfig
+		config.Transport = testCase.Transport
+		t.Logf("And given Client trusting server's certificate with %s", testCase.Name)
+		client, err := NewClient(config)
+		assert.NoError(t, err)
+
+		t.Logf("When calling GET")
+		var dummyResponse DummyResponse
+		err = client.Get(context.Background(), server.URL, &dummyResponse)
+
+		t.Logf("Should return DummyResponse")
+		assert.NoError(t, err)
+		assert.Equal(t, DummyResponse{Title: "Jan", Id: 1}, dummyResponse)
+	}
+}
+
+func TestClient_GetOverTLSWithUnknownAuthority(t *testing.T) {
+	t.Logf("Given HTTPS server returning 200 status")
+	callCount := make(map[string]int)
+	server := httptest.NewTLSServer(requestHandlerWithBody(200, &callCount, DummyResponse{Title: "Jan", Id: 1}))
+	defer server.Close()
+
+	t.Logf("And given Client not trusting server's certificate")
+	client, _ := NewClient(validClientConfig)
+
+	t.Logf("When calling GET")
+	var dummyResponse DummyResponse
+	err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+	t.Logf("Should return ClientError with network message")
+	var expectedError *ClientError
+	assert.True(t, errors.As(err, &expectedError))
+	assert.Equal(t, "network error", expectedError.Message)
+	assert.Equal(t, 0, callCount["/"])
+}
+
+func TestClient_GetOverMutualTLS(t *testing.T) {
+	t.Logf("Given client certificate")
+	clientCertificate, clientCA := newClientCertificate(t)
+
+	t.Logf("And given HTTPS server requiring client certificates")
+	callCount := make(map[string]int)
+	server := httptest.NewUnstartedServer(requestHandlerWithBody(200, &callCount, DummyResponse{Title: "Jan", Id: 1}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCA}
+	server.StartTLS()
+	defer server.Close()
+
+	testCases := []struct {
+		Certificates      []tls.Certificate
+		ExpectedCallCount int
+		ExpectedError     bool
+	}{
+		{Certificates: []tls.Certificate{clientCertificate}, ExpectedCallCount: 1, ExpectedError: false},
+		{Certificates: nil, ExpectedCallCount: 0, ExpectedError: true},
+	}
+
+	for _, testCase := range testCases {
+		config := validClientConfig
+		config.Transport = TransportConfig{
+			RootCAs:            certPool(server.Certificate()),
+			ClientCertificates: testCase.Certificates,
+		}
+		t.Logf("And given Client with %d client certificates", len(testCase.Certificates))
+		client, _ := NewClient(config)
+		callCount["/"] = 0
+
+		t.Logf("When calling GET")
+		var dummyResponse DummyResponse
+		err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+		t.Logf("Should call server %d times", testCase.ExpectedCallCount)
+		assert.Equal(t, testCase.ExpectedError, err != nil)
+		assert.Equal(t, testCase.ExpectedCallCount, callCount["/"])
+	}
+}
+
+func TestClient_GetOverHTTP2(t *testing.T) {
+	t.Logf("Given HTTPS server with HTTP/2 enabled returning request's protocol")
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte(`{"title":"` + req.Proto + `"}`))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	testCases := []struct {
+		DisableHTTP2  bool
+		ExpectedProto string
+	}{
+		{DisableHTTP2: false, ExpectedProto: "HTTP/2.0"},
+		{DisableHTTP2: true, ExpectedProto: "HTTP/1.1"},
+	}
+
+	for _, testCase := range testCases {
+		config := validClientConfig
+		config.Transport = TransportConfig{RootCAs: certPool(server.Certificate()), DisableHTTP2: testCase.DisableHTTP2}
+		t.Logf("And given Client with disableHTTP2=%t", testCase.DisableHTTP2)
+		client, _ := NewClient(config)
+
+		t.Logf("When calling GET")
+		var dummyResponse DummyResponse
+		err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+		t.Logf("Should use %s", testCase.ExpectedProto)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.ExpectedProto, dummyResponse.Title)
+	}
+}
+
+func TestClient_GetThroughProxy(t *testing.T) {
+	t.Logf("Given proxy server returning 200 status")
+	var proxiedUrl string
+	proxy := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		proxiedUrl = req.URL.String()
+		res.Write([]byte(`{"id":1,"title":"Jan"}`))
+	}))
+	defer proxy.Close()
+	proxyUrl, _ := url.Parse(proxy.URL)
+
+	t.Logf("And given Client with proxy")
+	config := validClientConfig
+	config.Transport = TransportConfig{Proxy: proxyUrl}
+	client, _ := NewClient(config)
+
+	t.Logf("When calling GET")
+	var dummyResponse DummyResponse
+	err := client.Get(context.Background(), "http://inventory.local/items", &dummyResponse)
+
+	t.Logf("Should send request through the proxy")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://inventory.local/items", proxiedUrl)
+	assert.Equal(t, DummyResponse{Title: "Jan", Id: 1}, dummyResponse)
+}
+
+func TestClient_GetWithCustomRoundTripperAndHttpClient(t *testing.T) {
+	t.Logf("Given HTTP server returning 200 status")
+	callCount := make(map[string]int)
+	server := httptest.NewServer(requestHandlerWithBody(200, &callCount, DummyResponse{Title: "Jan", Id: 1}))
+	defer server.Close()
+
+	roundTripper := &countingRoundTripper{}
+	testCases := []struct {
+		Name   string
+		Config ClientConfig
+	}{
+		{Name: "RoundTripper", Config: ClientConfig{RoundTripper: roundTripper}},
+		{Name: "HttpClient", Config: ClientConfig{HttpClient: &http.Client{Transport: roundTripper}}},
+	}
+
+	for _, testCase := range testCases {
+		config := testCase.Config
+		config.Retries = validClientConfig.Retries
+		config.Timeout = validClientConfig.Timeout
+		t.Logf("And given Client with custom %s", testCase.Name)
+		client, _ := NewClient(config)
+		roundTripper.calls = 0
+
+		t.Logf("When calling GET")
+		var dummyResponse DummyResponse
+		err := client.Get(context.Background(), server.URL, &dummyResponse)
+
+		t.Logf("Should send request with provided %s", testCase.Name)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, roundTripper.calls)
+		assert.Equal(t, DummyResponse{Title: "Jan", Id: 1}, dummyResponse)
+	}
+}
+
+type countingRoundTripper struct {
+	calls int
+}
+
+func (r *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.calls++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func certPool(certificate *x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	return pool
+}
+
+func encodeCertificate(certificate *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw})
+}
+
+func newClientCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "inventory-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: certificate}, certPool(certificate)
+}
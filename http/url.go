@@ -0,0 +1,156 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Values of path params of a route template, formatted with fmt.Sprint
+type PathParams map[string]interface{}
+
+var pathParamPattern = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// Builds request urls by resolving route templates such as /inventory/{id} against a base url.
+//
+// The template is appended to the path of the base url, so its prefix is preserved regardless of trailing slashes.
+type URLBuilder struct {
+	base url.URL
+}
+
+// Url built from a route template, Route is the template itself so it can be used as a low-cardinality label, see WithRoute
+type RouteURL struct {
+	Route string
+	URL   *url.URL
+}
+
+func (r *RouteURL) String() string {
+	return r.URL.String()
+}
+
+type routeKey struct{}
+
+// Returns a copy of ctx labelling requests sent with it with route, usually RouteURL.Route.
+// The label is logged with the requests and counted in Metrics.Routes, urls are not used as labels
+// as their params make them unbounded
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+func routeOf(ctx context.Context) string {
+	route, _ := ctx.Value(routeKey{}).(string)
+	return route
+}
+
+func NewURLBuilder(base url.URL) *URLBuilder {
+	return &URLBuilder{base: base}
+}
+
+// Resolves route against the base url, path params are escaped and query is encoded with EncodeQuery
+// and merged with the query of the base url.
+//
+// In case a param used by the route is missing or its value is "." or ".." (which would change the path
+// once resolved by the server) it returns RouteParamError.
+func (b *URLBuilder) Build(route string, params PathParams, query interface{}) (*RouteURL, error) {
+	var paramError *RouteParamError
+	escapedRoute := pathParamPattern.ReplaceAllStringFunc(route, func(match string) string {
+		name := match[1 : len(match)-1]
+		value, ok := params[name]
+		formatted := fmt.Sprint(value)
+		switch {
+		case paramError != nil:
+		case !ok:
+			paramError = &RouteParamError{Route: route, Param: name}
+		case formatted == "." || formatted == "..":
+			paramError = &RouteParamError{Route: route, Param: name, Value: formatted}
+		default:
+			return url.PathEscape(formatted)
+		}
+		return match
+	})
+	if paramError != nil {
+		return nil, paramError
+	}
+
+	encoded, err := EncodeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := joinURL(b.base, escapedRoute, encoded)
+	if err != nil {
+		return nil, err
+	}
+	return &RouteURL{Route: route, URL: resolved}, nil
+}
+
+// Appends already escaped path to the path of base and merges query with the query of base
+func joinURL(base url.URL, escapedPath string, query url.Values) (*url.URL, error) {
+	resolved := base
+	rawPath := strings.TrimSuffix(base.EscapedPath(), "/")
+	if escapedPath != "" {
+		rawPath += "/" + strings.TrimPrefix(escapedPath, "/")
+	}
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	resolved.Path = path
+	resolved.RawPath = rawPath
+
+	values := resolved.Query()
+	for key, value := range query {
+		values[key] = value
+	}
+	resolved.RawQuery = values.Encode()
+	return &resolved, nil
+}
+
+// Encodes url.Values, map[string]string or a flat struct into query params, nil encodes to no params.
+//
+// Field names of a struct are taken from url tags, then from json tags and finally from field names,
+// omitempty option skips zero values and slices are encoded as repeated params.
+func EncodeQuery(query interface{}) (url.Values, error) {
+	switch typed := query.(type) {
+	case nil:
+		return url.Values{}, nil
+	case url.Values:
+		return typed, nil
+	case map[string]string:
+		values := url.Values{}
+		for key, value := range typed {
+			values.Set(key, value)
+		}
+		return values, nil
+	}
+
+	source := reflect.ValueOf(query)
+	if source.Kind() == reflect.Ptr && source.IsNil() {
+		return url.Values{}, nil
+	}
+	source = reflect.Indirect(source)
+	if source.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("query does not support %T", query)
+	}
+	values := url.Values{}
+	for i := 0; i < source.NumField(); i++ {
+		name, omitEmpty := taggedFieldName(source.Type().Field(i), "url")
+		if name == "" {
+			continue
+		}
+		fieldValue := source.Field(i)
+		if omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+		formatted, err := formatFormValue(fieldValue)
+		if err != nil {
+			return nil, err
+		}
+		if len(formatted) > 0 {
+			values[name] = formatted
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type DummyQuery struct {
+	Name   string   `url:"name"`
+	Limit  int      `url:"limit,omitempty"`
+	Tags   []string `url:"tag"`
+	Sort   string   `json:"sort,omitempty"`
+	Hidden string   `url:"-"`
+	hidden string
+}
+
+func TestURLBuilder_Build(t *testing.T) {
+	tests := []struct {
+		base     string
+		route    string
+		params   PathParams
+		query    interface{}
+		expected string
+	}{
+		{base: "http://localhost:8000", route: "/inventory", expected: "http://localhost:8000/inventory"},
+		{base: "http://localhost:8000/", route: "/inventory", expected: "http://localhost:8000/inventory"},
+		{base: "http://localhost:8000/api", route: "/inventory/{id}", params: PathParams{"id": 7}, expected: "http://localhost:8000/api/inventory/7"},
+		{base: "http://localhost:8000/api/", route: "inventory/{id}", params: PathParams{"id": 7}, expected: "http://localhost:8000/api/inventory/7"},
+		{base: "http://localhost:8000", route: "/inventory/{id}", params: PathParams{"id": "a/b c"}, expected: "http://localhost:8000/inventory/a%2Fb%20c"},
+		{base: "http://localhost:8000/my%2Fapi", route: "/inventory", expected: "http://localhost:8000/my%2Fapi/inventory"},
+		{base: "http://localhost:8000?key=secret", route: "/inventory", query: map[string]string{"page": "2"}, expected: "http://localhost:8000/inventory?key=secret&page=2"},
+		{base: "http://localhost:8000", route: "/inventory", query: DummyQuery{Name: "Jan", Tags: []string{"a", "b"}, Hidden: "x"}, expected: "http://localhost:8000/inventory?name=Jan&tag=a&tag=b"},
+		{base: "http://localhost:8000", route: "/inventory", query: &DummyQuery{Limit: 10, Sort: "name"}, expected: "http://localhost:8000/inventory?limit=10&name=&sort=name"},
+		{base: "http://localhost:8000", route: "/inventory", query: (*DummyQuery)(nil), expected: "http://localhost:8000/inventory"},
+	}
+	for _, test := range tests {
+		t.Logf("Given URLBuilder with base %s", test.base)
+		base, _ := url.Parse(test.base)
+		builder := NewURLBuilder(*base)
+
+		t.Logf("When building route %s with params %v and query %+v", test.route, test.params, test.query)
+		routeUrl, err := builder.Build(test.route, test.params, test.query)
+
+		t.Logf("Should return %s along with the route", test.expected)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, routeUrl.String())
+		assert.Equal(t, test.route, routeUrl.Route)
+	}
+}
+
+func TestURLBuilder_BuildWithMissingParam(t *testing.T) {
+	t.Logf("Given URLBuilder")
+	base, _ := url.Parse("http://localhost:8000")
+	builder := NewURLBuilder(*base)
+
+	t.Logf("When building route without its path param")
+	routeUrl, err := builder.Build("/inventory/{id}", PathParams{"name": "Jan"}, nil)
+
+	t.Logf("Should return RouteParamError")
+	assert.Nil(t, routeUrl)
+	assert.Equal(t, &RouteParamError{Route: "/inventory/{id}", Param: "id"}, err)
+}
+
+func TestURLBuilder_BuildWithDotSegmentParam(t *testing.T) {
+	t.Logf("Given URLBuilder")
+	base, _ := url.Parse("http://localhost:8000")
+	builder := NewURLBuilder(*base)
+
+	for _, value := range []string{".", ".."} {
+		t.Logf("When building route with path param %s", value)
+		routeUrl, err := builder.Build("/inventory/{id}/history", PathParams{"id": value}, nil)
+
+		t.Logf("Should return RouteParamError with the value")
+		assert.Nil(t, routeUrl)
+		assert.Equal(t, &RouteParamError{Route: "/inventory/{id}/history", Param: "id", Value: value}, err)
+	}
+}
+
+func TestURLBuilder_BuildWithUnsupportedQuery(t *testing.T) {
+	t.Logf("Given URLBuilder")
+	base, _ := url.Parse("http://localhost:8000")
+	builder := NewURLBuilder(*base)
+
+	t.Logf("When building route with query which is not a struct nor a map")
+	_, err := builder.Build("/inventory", nil, 7)
+
+	t.Logf("Should return an error")
+	assert.EqualError(t, err, "query does not support int")
+}
+
+func TestClient_CountsRequestsByRoute(t *testing.T) {
+	t.Logf("Given HTTP server returning 200 status for /items/1 and 404 for the others")
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/items/1" {
+			res.WriteHeader(404)
+			return
+		}
+		res.Write([]byte(`{"id": 1}`))
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client, _ := NewClient(validClientConfig)
+	base, _ := url.Parse(server.URL)
+	builder := NewURLBuilder(*base)
+
+	t.Logf("When calling GET for two ids with the route and once without it")
+	for _, id := range []int{1, 2} {
+		routeUrl, _ := builder.Build("/items/{id}", PathParams{"id": id}, nil)
+		client.Get(WithRoute(context.Background(), routeUrl.Route), routeUrl.String(), &DummyResponse{})
+	}
+	client.Get(context.Background(), server.URL+"/items/1", &DummyResponse{})
+
+	t.Logf("Should count the labelled calls under the route template")
+	assert.Equal(t, map[string]RouteMetrics{"/items/{id}": {Requests: 2, Errors: 1}}, client.Metrics().Routes)
+	assert.Equal(t, int64(3), client.Metrics().Requests)
+}
@@ -0,0 +1,395 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"test2/clock"
+	"test2/http"
+	"time"
+)
+
+const defaultFlushInterval = 5 * time.Second
+
+type CacheConfig struct {
+	// How long fetched items are served without calling the service, zero means every read calls it
+	// and the cache is only used when the service is unavailable
+	TTL time.Duration
+	// Oldest items served when the service is unavailable, zero means any age
+	MaxStale time.Duration
+	// Optional store which keeps the cache across restarts, e.g. FileStore, the cache is kept in memory only when nil
+	Store CacheStore
+	// Optional, called for every queued write which the service rejected during the replay
+	OnConflict func(Conflict)
+}
+
+// Describes where a value served by CachedClient comes from
+type Freshness struct {
+	// True when the value is served from the cache because the service is unavailable
+	Stale bool
+	// When the value was fetched from the service, zero when it was never fetched
+	FetchedAt time.Time
+	// Error of the call which failed, set only when Stale
+	Err error
+}
+
+// Persisted state of the cache, written by CachedClient after every change of the cached items.
+// Items fetched again without changes are not written, so their FetchedAt is persisted along with the next change
+type CacheSnapshot struct {
+	Items []CachedItem `json:"items"`
+	// When the whole collection was fetched, zero when it was never fetched
+	ListedAt time.Time `json:"listed_at"`
+}
+
+type CachedItem struct {
+	Item      Inventory `json:"item"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Keeps CacheSnapshot across restarts
+type CacheStore interface {
+	// Returns an empty snapshot when nothing was saved yet
+	Load() (CacheSnapshot, error)
+	Save(snapshot CacheSnapshot) error
+}
+
+// Decorates the Client with a read-through cache of items and an outbox of writes made while the service is unavailable.
+//
+// Reads are served from the cache while they're fresher than TTL, otherwise they call the service and fall back to
+// the cache (marked as Stale) when it's unavailable: a network error, a timeout, 5xx or 429 status.
+//
+// Writes made while the service is unavailable are queued and replayed in order once it becomes available,
+// i.e. before the next successful call, with Flush or RunOutbox. Writes rejected during the replay are dropped
+// and reported to CacheConfig.OnConflict.
+type CachedClient struct {
+	client *Client
+	config CacheConfig
+
+	mutex    sync.Mutex
+	items    map[int]CachedItem
+	listed   []int
+	listedAt time.Time
+	// Incremented on every change, saves of snapshots older than the saved one are skipped
+	version int64
+
+	saveMutex sync.Mutex
+	saved     int64
+
+	outbox *outbox
+}
+
+// Creates CachedClient around client, loading the cache from CacheConfig.Store
+//
+// If TTL or MaxStale of CacheConfig is negative it returns CacheConfigNegativeError,
+// errors of loading the store are returned as they are.
+func NewCachedClient(client *Client, config CacheConfig) (*CachedClient, error) {
+	if config.TTL < 0 || config.MaxStale < 0 {
+		return nil, CacheConfigNegativeError
+	}
+	cached := &CachedClient{client: client, config: config, items: map[int]CachedItem{}, outbox: &outbox{}}
+	if config.Store != nil {
+		snapshot, err := config.Store.Load()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range snapshot.Items {
+			cached.items[item.Item.Id] = item
+		}
+		if !snapshot.ListedAt.IsZero() {
+			cached.listedAt = snapshot.ListedAt
+			for _, item := range snapshot.Items {
+				cached.listed = append(cached.listed, item.Item.Id)
+			}
+		}
+	}
+	return cached, nil
+}
+
+func (c *CachedClient) GetItems(ctx context.Context) ([]Inventory, Freshness, error) {
+	if items, listedAt, ok := c.cachedList(); ok && c.fresh(listedAt) {
+		return items, Freshness{FetchedAt: listedAt}, nil
+	}
+	items, err := c.client.GetItems(ctx)
+	if err != nil {
+		cached, listedAt, ok := c.cachedList()
+		if ok && c.servesStale(ctx, err, listedAt) {
+			return cached, Freshness{Stale: true, FetchedAt: listedAt, Err: err}, nil
+		}
+		return nil, Freshness{}, err
+	}
+	c.flushAfterSuccess(ctx)
+
+	now := c.now()
+	c.mutex.Lock()
+	changed := c.listedAt.IsZero() || len(c.listed) != len(items)
+	for i, item := range items {
+		previous, ok := c.items[item.Id]
+		changed = changed || !ok || !previous.Item.Equal(item) || c.listed[i] != item.Id
+		c.items[item.Id] = CachedItem{Item: item, FetchedAt: now}
+	}
+	c.listed = c.listed[:0]
+	for _, item := range items {
+		c.listed = append(c.listed, item.Id)
+	}
+	c.listedAt = now
+	c.mutex.Unlock()
+	if changed {
+		c.save()
+	}
+	return items, Freshness{FetchedAt: now}, nil
+}
+
+func (c *CachedClient) GetItem(ctx context.Context, id int) (Inventory, Freshness, error) {
+	cached, ok := c.cachedItem(id)
+	if ok && c.fresh(cached.FetchedAt) {
+		return cached.Item, Freshness{FetchedAt: cached.FetchedAt}, nil
+	}
+	item, err := c.client.GetItem(ctx, id)
+	if err != nil {
+		if ok && c.servesStale(ctx, err, cached.FetchedAt) {
+			return cached.Item, Freshness{Stale: true, FetchedAt: cached.FetchedAt, Err: err}, nil
+		}
+		var httpError *http.ClientHttpError
+		if errors.As(err, &httpError) && httpError.StatusCode == 404 {
+			c.forget(id)
+		}
+		return Inventory{}, Freshness{}, err
+	}
+	c.flushAfterSuccess(ctx)
+
+	now := c.now()
+	c.store(item, now)
+	return item, Freshness{FetchedAt: now}, nil
+}
+
+// Creates the item, when the service is unavailable it's queued in the outbox and returned as the second value
+// instead of the created item. Writes which are already queued are replayed first, so the order is kept.
+// Invalid items are rejected with ValidationError and never queued.
+//
+// The item is sent with an Idempotency-Key header, which is kept in the queued entry and sent again with the replays,
+// so the service can skip an item created by a request which failed only on the way back
+func (c *CachedClient) CreateItem(ctx context.Context, createInventory CreateInventory) (Inventory, *OutboxEntry, error) {
+	if err := createInventory.ValidateWith(c.client.Limits()); err != nil {
+		return Inventory{}, nil, err
+	}
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return Inventory{}, nil, err
+	}
+	if err := c.Flush(ctx); err != nil {
+		if ctx.Err() != nil {
+			return Inventory{}, nil, err
+		}
+		return Inventory{}, c.outbox.push(createInventory, key, c.now()), nil
+	}
+	item, err := c.client.CreateItem(http.WithHeaders(ctx, http.Headers{"Idempotency-Key": key}), createInventory)
+	if err != nil {
+		if ctx.Err() == nil && isUnavailable(err) {
+			return Inventory{}, c.outbox.push(createInventory, key, c.now()), nil
+		}
+		return Inventory{}, nil, err
+	}
+	c.store(item, c.now())
+	return item, nil, nil
+}
+
+// Replays queued writes in order, stops at the first one which fails as the service is unavailable
+// and returns its error. Writes rejected by the service are dropped and reported to CacheConfig.OnConflict
+func (c *CachedClient) Flush(ctx context.Context) error {
+	return c.outbox.replay(func(entry OutboxEntry) error {
+		item, err := c.client.CreateItem(http.WithHeaders(ctx, http.Headers{"Idempotency-Key": entry.IdempotencyKey}), entry.Create)
+		switch {
+		case err == nil:
+			c.store(item, c.now())
+			return nil
+		case ctx.Err() != nil || isUnavailable(err):
+			return err
+		default:
+			if c.config.OnConflict != nil {
+				c.config.OnConflict(Conflict{Entry: entry, Err: err})
+			}
+			return nil
+		}
+	})
+}
+
+// Writes waiting in the outbox, oldest first
+func (c *CachedClient) Pending() []OutboxEntry {
+	return c.outbox.entries()
+}
+
+// Flushes the outbox every interval (defaults to 5s) until ctx is done, so queued writes are replayed even when
+// there are no other calls
+func (c *CachedClient) RunOutbox(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultFlushInterval
+	}
+	for {
+		select {
+		case <-c.clock().After(interval):
+		case <-ctx.Done():
+			return
+		}
+		c.Flush(ctx)
+	}
+}
+
+// A successful call means the service is available again
+func (c *CachedClient) flushAfterSuccess(ctx context.Context) {
+	if len(c.outbox.entries()) > 0 {
+		c.Flush(ctx)
+	}
+}
+
+func (c *CachedClient) cachedList() ([]Inventory, time.Time, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.listedAt.IsZero() {
+		return nil, time.Time{}, false
+	}
+	items := make([]Inventory, 0, len(c.listed))
+	for _, id := range c.listed {
+		if cached, ok := c.items[id]; ok {
+			items = append(items, cached.Item)
+		}
+	}
+	return items, c.listedAt, true
+}
+
+func (c *CachedClient) cachedItem(id int) (CachedItem, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cached, ok := c.items[id]
+	return cached, ok
+}
+
+func (c *CachedClient) store(item Inventory, fetchedAt time.Time) {
+	c.mutex.Lock()
+	previous, ok := c.items[item.Id]
+	c.items[item.Id] = CachedItem{Item: item, FetchedAt: fetchedAt}
+	c.mutex.Unlock()
+	if !ok || !previous.Item.Equal(item) {
+		c.save()
+	}
+}
+
+func (c *CachedClient) forget(id int) {
+	c.mutex.Lock()
+	delete(c.items, id)
+	for i, listed := range c.listed {
+		if listed == id {
+			c.listed = append(c.listed[:i:i], c.listed[i+1:]...)
+			break
+		}
+	}
+	c.mutex.Unlock()
+	c.save()
+}
+
+// Persists the cache, failures only make the next start colder so they're not returned.
+// Saves run one at a time and a snapshot taken before the last saved one is skipped, so it can't overwrite it
+func (c *CachedClient) save() {
+	if c.config.Store == nil {
+		return
+	}
+	c.mutex.Lock()
+	c.version++
+	version := c.version
+	snapshot := CacheSnapshot{ListedAt: c.listedAt}
+	for _, id := range c.listed {
+		snapshot.Items = append(snapshot.Items, c.items[id])
+	}
+	for id, item := range c.items {
+		if !contains(c.listed, id) {
+			snapshot.Items = append(snapshot.Items, item)
+		}
+	}
+	c.mutex.Unlock()
+
+	c.saveMutex.Lock()
+	defer c.saveMutex.Unlock()
+	if version < c.saved {
+		return
+	}
+	c.config.Store.Save(snapshot)
+	c.saved = version
+}
+
+func (c *CachedClient) fresh(fetchedAt time.Time) bool {
+	return c.config.TTL > 0 && c.now().Sub(fetchedAt) < c.config.TTL
+}
+
+func (c *CachedClient) servesStale(ctx context.Context, err error, fetchedAt time.Time) bool {
+	if ctx.Err() != nil || !isUnavailable(err) {
+		return false
+	}
+	return c.config.MaxStale == 0 || c.now().Sub(fetchedAt) <= c.config.MaxStale
+}
+
+func (c *CachedClient) now() time.Time {
+	return c.clock().Now()
+}
+
+func (c *CachedClient) clock() clock.Clock {
+	return clock.OrSystem(c.client.Config().Clock)
+}
+
+// Network errors, timeouts, 5xx and 429 statuses mean the service can't be reached right now
+func isUnavailable(err error) bool {
+	var httpError *http.ClientHttpError
+	if errors.As(err, &httpError) {
+		return httpError.StatusCode >= 500 || httpError.StatusCode == 429
+	}
+	var timeoutError *http.TimeoutError
+	var clientError *http.ClientError
+	return errors.As(err, &timeoutError) || errors.As(err, &clientError) && clientError.Message == "network error"
+}
+
+func contains(ids []int, id int) bool {
+	for _, listed := range ids {
+		if listed == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Keeps CacheSnapshot as a JSON file, written to a temporary file first so a crash never leaves it half-written
+type FileStore struct {
+	Path string
+}
+
+func (s FileStore) Load() (CacheSnapshot, error) {
+	var snapshot CacheSnapshot
+	content, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return snapshot, err
+	}
+	return snapshot, json.Unmarshal(content, &snapshot)
+}
+
+func (s FileStore) Save(snapshot CacheSnapshot) error {
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	temporary, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(content); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporary.Name(), s.Path)
+}
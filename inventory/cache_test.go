@@ -0,0 +1,270 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	corehttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"test2/clock"
+	"test2/http"
+	"test2/http/retry"
+	"testing"
+	"time"
+)
+
+// Inventory server which can be taken down, items named "conflict" are rejected with 409 status
+type flakyServer struct {
+	mutex   sync.Mutex
+	down    int32
+	gets    int32
+	items   []Inventory
+	created []string
+	// Idempotency-Key headers of all of the writes, including the failed ones
+	keys []string
+}
+
+func (s *flakyServer) ServeHTTP(res corehttp.ResponseWriter, req *corehttp.Request) {
+	if req.Method != corehttp.MethodGet {
+		s.mutex.Lock()
+		s.keys = append(s.keys, req.Header.Get("Idempotency-Key"))
+		s.mutex.Unlock()
+	}
+	if atomic.LoadInt32(&s.down) == 1 {
+		res.WriteHeader(503)
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if req.Method == corehttp.MethodGet {
+		atomic.AddInt32(&s.gets, 1)
+		json.NewEncoder(res).Encode(s.items)
+		return
+	}
+	var createInventory CreateInventory
+	json.NewDecoder(req.Body).Decode(&createInventory)
+	if createInventory.Name == "conflict" {
+		res.WriteHeader(409)
+		return
+	}
+	s.created = append(s.created, createInventory.Name)
+	item := Inventory{Id: len(s.items) + 1, Name: createInventory.Name}
+	s.items = append(s.items, item)
+	res.WriteHeader(201)
+	json.NewEncoder(res).Encode(item)
+}
+
+func (s *flakyServer) setDown(down bool) {
+	if down {
+		atomic.StoreInt32(&s.down, 1)
+	} else {
+		atomic.StoreInt32(&s.down, 0)
+	}
+}
+
+func newCachedTestClient(t *testing.T, server *httptest.Server, manual *clock.Manual, config CacheConfig) *CachedClient {
+	serverUrl, _ := url.Parse(server.URL)
+	client, err := NewClient(ClientConfig{
+		Timeout:       time.Second,
+		Url:           *serverUrl,
+		RetriesConfig: retry.RetriesConfig{MaxRetries: 1, Delay: time.Millisecond, Factor: 2, Clock: clock.System},
+		Clock:         manual,
+	})
+	assert.NoError(t, err)
+	cached, err := NewCachedClient(client, config)
+	assert.NoError(t, err)
+	return cached
+}
+
+func TestCachedClient_GetItemsWhenServiceIsDown(t *testing.T) {
+	t.Logf("Given inventory server with an item")
+	flaky := &flakyServer{items: []Inventory{{Id: 1, Name: "Jan"}}}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+
+	t.Logf("And given CachedClient which fetched the items a minute ago")
+	manual := clock.NewManual(time.Now())
+	fetchedAt := manual.Now()
+	client := newCachedTestClient(t, server, manual, CacheConfig{})
+	_, freshness, err := client.GetItems(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, freshness.Stale)
+	manual.Advance(time.Minute)
+
+	t.Logf("When the server goes down and items are fetched again")
+	flaky.setDown(true)
+	items, freshness, err := client.GetItems(context.Background())
+
+	t.Logf("Should serve the cached items marked as stale")
+	assert.NoError(t, err)
+	assert.Equal(t, []Inventory{{Id: 1, Name: "Jan"}}, items)
+	assert.True(t, freshness.Stale)
+	assert.Equal(t, fetchedAt, freshness.FetchedAt)
+	var httpError *http.ClientHttpError
+	assert.True(t, errors.As(freshness.Err, &httpError))
+
+	t.Logf("And should serve the item from the fetched list")
+	item, freshness, err := client.GetItem(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, Inventory{Id: 1, Name: "Jan"}, item)
+	assert.True(t, freshness.Stale)
+}
+
+func TestCachedClient_GetItemsWithTTLAndMaxStale(t *testing.T) {
+	t.Logf("Given inventory server with an item")
+	flaky := &flakyServer{items: []Inventory{{Id: 1, Name: "Jan"}}}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+
+	t.Logf("And given CachedClient with 1m TTL and 1h max stale")
+	manual := clock.NewManual(time.Now())
+	client := newCachedTestClient(t, server, manual, CacheConfig{TTL: time.Minute, MaxStale: time.Hour})
+
+	t.Logf("When fetching items twice within the TTL")
+	client.GetItems(context.Background())
+	manual.Advance(30 * time.Second)
+	_, freshness, err := client.GetItems(context.Background())
+
+	t.Logf("Should call the server once")
+	assert.NoError(t, err)
+	assert.False(t, freshness.Stale)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&flaky.gets))
+
+	t.Logf("When the server goes down for longer than max stale")
+	flaky.setDown(true)
+	manual.Advance(2 * time.Hour)
+	items, _, err := client.GetItems(context.Background())
+
+	t.Logf("Should return the error of the server")
+	assert.Nil(t, items)
+	var httpError *http.ClientHttpError
+	assert.True(t, errors.As(err, &httpError))
+}
+
+func TestCachedClient_CreateItemWhileOffline(t *testing.T) {
+	t.Logf("Given inventory server which is down")
+	flaky := &flakyServer{}
+	flaky.setDown(true)
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+
+	t.Logf("And given CachedClient reporting conflicts")
+	var conflicts []Conflict
+	client := newCachedTestClient(t, server, clock.NewManual(time.Now()), CacheConfig{
+		OnConflict: func(conflict Conflict) { conflicts = append(conflicts, conflict) },
+	})
+
+	t.Logf("When creating 3 items while offline")
+	for _, name := range []string{"first", "conflict", "second"} {
+		item, entry, err := client.CreateItem(context.Background(), CreateInventory{Name: name})
+		assert.NoError(t, err)
+		assert.Equal(t, Inventory{}, item)
+		assert.Equal(t, name, entry.Create.Name)
+	}
+
+	t.Logf("Should queue them in order with the idempotency key of the direct attempt")
+	pending := client.Pending()
+	assert.Len(t, pending, 3)
+	assert.Equal(t, []int64{1, 2, 3}, []int64{pending[0].Id, pending[1].Id, pending[2].Id})
+	assert.Equal(t, pending[0].IdempotencyKey, flaky.keys[0])
+	assert.NotEqual(t, pending[1].IdempotencyKey, pending[2].IdempotencyKey)
+
+	t.Logf("When the server comes back and items are fetched")
+	flaky.setDown(false)
+	_, freshness, err := client.GetItems(context.Background())
+
+	t.Logf("Should replay the writes in order and report the rejected one")
+	assert.NoError(t, err)
+	assert.False(t, freshness.Stale)
+	assert.Equal(t, []string{"first", "second"}, flaky.created)
+	keys := []string{pending[0].IdempotencyKey, pending[1].IdempotencyKey, pending[2].IdempotencyKey}
+	assert.Equal(t, keys, flaky.keys[len(flaky.keys)-3:])
+	assert.Empty(t, client.Pending())
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, "conflict", conflicts[0].Entry.Create.Name)
+	var httpError *http.ClientHttpError
+	assert.True(t, errors.As(conflicts[0].Err, &httpError))
+	assert.Equal(t, 409, httpError.StatusCode)
+}
+
+func TestCachedClient_WithFileStore(t *testing.T) {
+	t.Logf("Given inventory server with an item")
+	flaky := &flakyServer{items: []Inventory{{Id: 1, Name: "Jan"}}}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+
+	t.Logf("And given CachedClient with FileStore which fetched the items")
+	store := FileStore{Path: filepath.Join(t.TempDir(), "cache.json")}
+	manual := clock.NewManual(time.Now())
+	first := newCachedTestClient(t, server, manual, CacheConfig{Store: store})
+	_, _, err := first.GetItems(context.Background())
+	assert.NoError(t, err)
+
+	t.Logf("When the server goes down and another CachedClient is created with the same store")
+	flaky.setDown(true)
+	second := newCachedTestClient(t, server, manual, CacheConfig{Store: store})
+	items, freshness, err := second.GetItems(context.Background())
+
+	t.Logf("Should serve the stored items marked as stale")
+	assert.NoError(t, err)
+	assert.Equal(t, []Inventory{{Id: 1, Name: "Jan"}}, items)
+	assert.True(t, freshness.Stale)
+}
+
+func TestCachedClient_SavesOnlyChanges(t *testing.T) {
+	t.Logf("Given inventory server with an item")
+	flaky := &flakyServer{items: []Inventory{{Id: 1, Name: "Jan"}}}
+	server := httptest.NewServer(flaky)
+	defer server.Close()
+
+	t.Logf("And given CachedClient with a store counting saves")
+	store := &countingStore{}
+	client := newCachedTestClient(t, server, clock.NewManual(time.Now()), CacheConfig{Store: store})
+
+	t.Logf("When fetching the items twice, changing the item and fetching them again")
+	for i := 0; i < 2; i++ {
+		_, _, err := client.GetItems(context.Background())
+		assert.NoError(t, err)
+	}
+	flaky.mutex.Lock()
+	flaky.items[0].Name = "Janusz"
+	flaky.mutex.Unlock()
+	_, _, err := client.GetItems(context.Background())
+
+	t.Logf("Should save only the first fetch and the change")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, store.saves)
+	assert.Equal(t, "Janusz", store.last.Items[0].Item.Name)
+}
+
+type countingStore struct {
+	saves int
+	last  CacheSnapshot
+}
+
+func (s *countingStore) Load() (CacheSnapshot, error) {
+	return CacheSnapshot{}, nil
+}
+
+func (s *countingStore) Save(snapshot CacheSnapshot) error {
+	s.saves++
+	s.last = snapshot
+	return nil
+}
+
+func TestNewCachedClientWithNegativeTTL(t *testing.T) {
+	t.Logf("Given CacheConfig with negative TTL")
+	config := CacheConfig{TTL: -time.Second}
+
+	t.Logf("When creating CachedClient")
+	client, err := NewCachedClient(&Client{}, config)
+
+	t.Logf("Should return CacheConfigNegativeError")
+	assert.Nil(t, client)
+	assert.Equal(t, CacheConfigNegativeError, err)
+}
@@ -2,61 +2,198 @@ package inventory
 
 import (
 	"context"
-	"fmt"
+	corehttp "net/http"
 	"net/url"
+	"sync"
+	"sync/atomic"
+	"test2/clock"
 	"test2/http"
 	"test2/http/retry"
 	"time"
 )
 
 type ClientConfig struct {
-	Timeout       time.Duration
-	Logging       bool
-	Url           url.URL
+	Timeout      time.Duration
+	TotalTimeout time.Duration
+	Logging      bool
+	Url          url.URL
+	// Optional replicas of the service (static or discovered from SRV records) balanced on every attempt,
+	// Url is ignored when they are set
+	Endpoints     http.EndpointsConfig
 	RetriesConfig retry.RetriesConfig
+	// Headers set on every outgoing request
+	Headers http.Headers
+	// Media type of request bodies, defaults to application/json. Responses are decoded
+	// according to their Content-Type with any of the shipped codecs or the ones from Codecs
+	ContentType      string
+	Codecs           []http.Codec
+	ContentTypeCheck http.ContentTypeCheck
+	Hedging          http.HedgingConfig
+	Coalescing       http.CoalescingConfig
+	Compression      http.CompressionConfig
+	Transport        http.TransportConfig
+	HttpClient       *corehttp.Client
+	RoundTripper     corehttp.RoundTripper
+	// Optional clock of the underlying http.Client, defaults to clock.System
+	Clock clock.Clock
+	// Limits items are validated against before they are sent, zero fields are not enforced
+	Limits Limits
 }
 
 type Client struct {
-	Url    url.URL
+	// Deprecated: Url passed to NewClient, it's not changed by Update. Use Config().Url instead
+	Url url.URL
+	// Deprecated: http.Client built by NewClient, it's not changed by Update. Use HttpClient instead
 	Client *http.Client
+	// Holds *clientState, swapped as a whole by Update so in-flight requests keep using the previous one
+	state       atomic.Value
+	updateMutex sync.Mutex
+	// Holds Limits fetched by RefreshLimits
+	limits atomic.Value
+}
+
+// Configuration of the Client along with everything built from it
+type clientState struct {
+	config ClientConfig
+	url    url.URL
+	client *http.Client
 }
 
 func NewClient(config ClientConfig) (*Client, error) {
-	client, err := http.NewClient(http.ClientConfig{
-		Timeout: config.Timeout,
-		Logging: config.Logging,
-		Retries: config.RetriesConfig,
-		Headers: http.Headers{
-			"Content-Type": "application/json",
-			"Accept":       "application/json",
-		}})
+	state, err := newClientState(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{Url: config.Url, Client: state.client}
+	client.state.Store(state)
+	return client, nil
+}
+
+// Builds the state, the long-lived parts of the previous http.Client (if any) are carried over
+func newClientState(config ClientConfig, previous *http.Client) (*clientState, error) {
+	httpConfig := http.ClientConfig{
+		Timeout:          config.Timeout,
+		TotalTimeout:     config.TotalTimeout,
+		Logging:          config.Logging,
+		Retries:          config.RetriesConfig,
+		Headers:          config.Headers,
+		Hedging:          config.Hedging,
+		Coalescing:       config.Coalescing,
+		Endpoints:        config.Endpoints,
+		Compression:      config.Compression,
+		ContentType:      config.ContentType,
+		Codecs:           config.Codecs,
+		ContentTypeCheck: config.ContentTypeCheck,
+		Transport:        config.Transport,
+		HttpClient:       config.HttpClient,
+		RoundTripper:     config.RoundTripper,
+		Clock:            config.Clock,
+	}
+	var client *http.Client
+	var err error
+	if previous == nil {
+		client, err = http.NewClient(httpConfig)
+	} else {
+		client, err = previous.Reconfigure(httpConfig)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &Client{
-		Url:    config.Url,
-		Client: client,
-	}, nil
+	baseUrl := config.Url
+	if len(config.Endpoints.Endpoints) > 0 || config.Endpoints.SRV.Name != "" {
+		// Relative urls are resolved against the endpoints by http.Client
+		baseUrl = url.URL{}
+	}
+	return &clientState{config: config, url: baseUrl, client: client}, nil
 }
 
+func (c *Client) current() *clientState {
+	return c.state.Load().(*clientState)
+}
+
+// Returns the config which is currently active
+func (c *Client) Config() ClientConfig {
+	return c.current().config
+}
+
+// Returns the underlying http.Client of the config which is currently active
+func (c *Client) HttpClient() *http.Client {
+	return c.current().client
+}
+
+// Stops health checks and discovery of the endpoints
+func (c *Client) Close() {
+	c.current().client.Close()
+}
+
+// Route templates of the inventory api, resolved against ClientConfig.Url
+const (
+	itemsRoute    = "/inventory"
+	itemRoute     = "/inventory/{id}"
+	eventsRoute   = "/inventory/events"
+	metadataRoute = "/inventory/metadata"
+)
+
 func (c *Client) GetItems(ctx context.Context) ([]Inventory, error) {
-	var items []Inventory
-	path := fmt.Sprintf("%s/inventory", c.Url.String())
-	err := c.Client.Get(ctx, path, &items)
+	state := c.current()
+	path, err := state.route(itemsRoute, nil)
+	if err != nil {
+		return nil, err
+	}
+	items, _, err := http.Get[[]Inventory](http.WithRoute(ctx, path.Route), state.client, path.String())
 	return items, err
-
 }
 
 func (c *Client) GetItem(ctx context.Context, id int) (Inventory, error) {
-	var item Inventory
-	path := fmt.Sprintf("%s/inventory/%d", c.Url.String(), id)
-	err := c.Client.Get(ctx, path, &item)
+	state := c.current()
+	path, err := state.route(itemRoute, http.PathParams{"id": id})
+	if err != nil {
+		return Inventory{}, err
+	}
+	item, _, err := http.Get[Inventory](http.WithRoute(ctx, path.Route), state.client, path.String())
 	return item, err
 }
 
 func (c *Client) CreateItem(ctx context.Context, createInventory CreateInventory) (Inventory, error) {
-	var item Inventory
-	path := fmt.Sprintf("%s/inventory", c.Url.String())
-	err := c.Client.Post(ctx, path, createInventory, &item)
+	item, _, err := c.CreateItemWithLocation(ctx, createInventory)
 	return item, err
 }
+
+// Creates the item and returns it along with the Location header of the response resolved against the Url,
+// location is nil when the server doesn't return one.
+// Invalid items are rejected with ValidationError without sending them, see Limits
+func (c *Client) CreateItemWithLocation(ctx context.Context, createInventory CreateInventory) (Inventory, *url.URL, error) {
+	if err := createInventory.ValidateWith(c.Limits()); err != nil {
+		return Inventory{}, nil, err
+	}
+	state := c.current()
+	path, err := state.route(itemsRoute, nil)
+	if err != nil {
+		return Inventory{}, nil, err
+	}
+	item, response, err := http.Post[CreateInventory, Inventory](http.WithRoute(ctx, path.Route), state.client, path.String(), createInventory)
+	if err != nil {
+		return item, nil, err
+	}
+	return item, response.Location(), nil
+}
+
+// Replaces the item of item.Id and returns it as stored by the service. Fields unknown to the client which were
+// received with the item (Inventory.Unknown) are sent back as they are, so the update doesn't drop them.
+// Invalid items are rejected with ValidationError without sending them, see Limits
+func (c *Client) UpdateItem(ctx context.Context, item Inventory) (Inventory, error) {
+	if err := item.ValidateWith(c.Limits()); err != nil {
+		return Inventory{}, err
+	}
+	state := c.current()
+	path, err := state.route(itemRoute, http.PathParams{"id": item.Id})
+	if err != nil {
+		return Inventory{}, err
+	}
+	updated, _, err := http.Put[Inventory, Inventory](http.WithRoute(ctx, path.Route), state.client, path.String(), item)
+	return updated, err
+}
+
+func (s *clientState) route(route string, params http.PathParams) (*http.RouteURL, error) {
+	return http.NewURLBuilder(s.url).Build(route, params, nil)
+}
@@ -0,0 +1,161 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	corehttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"test2/http"
+	"test2/http/retry"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, server *httptest.Server) *Client {
+	serverUrl, _ := url.Parse(server.URL)
+	client, err := NewClient(ClientConfig{
+		Timeout:       time.Second,
+		Url:           *serverUrl,
+		RetriesConfig: retry.RetriesConfig{MaxRetries: 3, Delay: time.Millisecond, Factor: 2},
+	})
+	assert.NoError(t, err)
+	return client
+}
+
+func TestClient_CreateItemWithLocation(t *testing.T) {
+	t.Logf("Given inventory server returning 201 status with Location")
+	server := httptest.NewServer(corehttp.HandlerFunc(func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		var createInventory CreateInventory
+		json.NewDecoder(req.Body).Decode(&createInventory)
+		res.Header().Set("Location", "/inventory/7")
+		res.WriteHeader(201)
+		json.NewEncoder(res).Encode(Inventory{Id: 7, Name: createInventory.Name, Description: createInventory.Description})
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When calling CreateItemWithLocation")
+	item, location, err := client.CreateItemWithLocation(context.Background(), CreateInventory{Name: "Jan", Description: "Kowalski"})
+
+	t.Logf("Should return created item with its location")
+	assert.NoError(t, err)
+	assert.Equal(t, Inventory{Id: 7, Name: "Jan", Description: "Kowalski"}, item)
+	assert.Equal(t, server.URL+"/inventory/7", location.String())
+}
+
+func TestClient_CreateItemWithoutLocation(t *testing.T) {
+	t.Logf("Given inventory server returning 201 status without Location")
+	server := httptest.NewServer(corehttp.HandlerFunc(func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		res.WriteHeader(201)
+		json.NewEncoder(res).Encode(Inventory{Id: 7})
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When calling CreateItemWithLocation")
+	item, location, err := client.CreateItemWithLocation(context.Background(), CreateInventory{Name: "Jan"})
+
+	t.Logf("Should return created item without location")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, item.Id)
+	assert.Nil(t, location)
+}
+
+func TestClient_UpdateItemSendsUnknownFieldsBack(t *testing.T) {
+	t.Logf("Given inventory server returning item with a field unknown to the client and echoing updates")
+	var method, path string
+	var sent map[string]interface{}
+	server := httptest.NewServer(corehttp.HandlerFunc(func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		if req.Method == corehttp.MethodGet {
+			res.Write([]byte(`{"id":7,"name":"Hammer","quantity":1,"supplier":"ACME"}`))
+			return
+		}
+		method, path = req.Method, req.URL.Path
+		body, _ := io.ReadAll(req.Body)
+		json.Unmarshal(body, &sent)
+		res.Write(body)
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When fetching the item and updating its quantity")
+	item, err := client.GetItem(context.Background(), 7)
+	assert.NoError(t, err)
+	item.Quantity = 2
+	updated, err := client.UpdateItem(context.Background(), item)
+
+	t.Logf("Should PUT the item with the unknown field and return the updated one")
+	assert.NoError(t, err)
+	assert.Equal(t, corehttp.MethodPut, method)
+	assert.Equal(t, "/inventory/7", path)
+	assert.Equal(t, "ACME", sent["supplier"])
+	assert.Equal(t, 2.0, sent["quantity"])
+	assert.True(t, item.Equal(updated))
+}
+
+func TestClient_GetItemWithBasePath(t *testing.T) {
+	t.Logf("Given inventory server behind /api/ prefix")
+	var path string
+	server := httptest.NewServer(corehttp.HandlerFunc(func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		path = req.URL.Path
+		json.NewEncoder(res).Encode(Inventory{Id: 7, Name: "Jan"})
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client with base url ending with a slash")
+	serverUrl, _ := url.Parse(server.URL + "/api/")
+	client, _ := NewClient(ClientConfig{
+		Timeout:       time.Second,
+		Url:           *serverUrl,
+		RetriesConfig: retry.RetriesConfig{MaxRetries: 3, Delay: time.Millisecond, Factor: 2},
+	})
+
+	t.Logf("When calling GetItem")
+	item, err := client.GetItem(context.Background(), 7)
+
+	t.Logf("Should call /api/inventory/7")
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/inventory/7", path)
+	assert.Equal(t, Inventory{Id: 7, Name: "Jan"}, item)
+}
+
+func TestClient_GetItemsWithEndpoints(t *testing.T) {
+	t.Logf("Given inventory server returning 503 status and another one behind /api prefix")
+	down := httptest.NewServer(corehttp.HandlerFunc(func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		res.WriteHeader(503)
+	}))
+	defer down.Close()
+	var path string
+	up := httptest.NewServer(corehttp.HandlerFunc(func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		path = req.URL.Path
+		json.NewEncoder(res).Encode([]Inventory{{Id: 7, Name: "Jan"}})
+	}))
+	defer up.Close()
+
+	t.Logf("And given Client balancing between them")
+	downUrl, _ := url.Parse(down.URL)
+	upUrl, _ := url.Parse(up.URL + "/api")
+	client, _ := NewClient(ClientConfig{
+		Timeout:       time.Second,
+		Endpoints:     http.EndpointsConfig{Endpoints: []http.Endpoint{{URL: *downUrl}, {URL: *upUrl}}},
+		RetriesConfig: retry.RetriesConfig{MaxRetries: 3, Delay: time.Millisecond, Factor: 2},
+	})
+	defer client.Close()
+
+	t.Logf("When calling GetItems")
+	items, err := client.GetItems(context.Background())
+
+	t.Logf("Should fail over to the second server")
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/inventory", path)
+	assert.Equal(t, []Inventory{{Id: 7, Name: "Jan"}}, items)
+}
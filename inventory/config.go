@@ -0,0 +1,265 @@
+package inventory
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"test2/http"
+	"test2/http/retry"
+	"time"
+)
+
+// Duration which is read from and written as a human-readable string, e.g. 1s, 500ms or 1m30s
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Serializable subset of ClientConfig which can be loaded from files, environment variables and flags.
+//
+// Sources are applied on top of each other, so the usual order is LoadFile, LoadEnv and then LoadFlags,
+// ClientConfig validates the result and turns it into ClientConfig.
+type Settings struct {
+	Url          string          `json:"url" yaml:"url" toml:"url"`
+	Timeout      Duration        `json:"timeout" yaml:"timeout" toml:"timeout"`
+	TotalTimeout Duration        `json:"total_timeout" yaml:"total_timeout" toml:"total_timeout"`
+	Logging      bool            `json:"logging" yaml:"logging" toml:"logging"`
+	ContentType  string          `json:"content_type" yaml:"content_type" toml:"content_type"`
+	Retries      RetriesSettings `json:"retries" yaml:"retries" toml:"retries"`
+	// Headers set on every outgoing request
+	Headers map[string]string `json:"headers" yaml:"headers" toml:"headers"`
+}
+
+type RetriesSettings struct {
+	MaxRetries int      `json:"max_retries" yaml:"max_retries" toml:"max_retries"`
+	Delay      Duration `json:"delay" yaml:"delay" toml:"delay"`
+	Factor     float64  `json:"factor" yaml:"factor" toml:"factor"`
+}
+
+// Field of Settings which can be set from an environment variable and a flag
+type settingsField struct {
+	env   string
+	flag  string
+	usage string
+	set   func(settings *Settings, value string) error
+}
+
+var settingsFields = []settingsField{
+	{env: "INVENTORY_URL", flag: "inventory-url", usage: "Base url of the inventory service",
+		set: stringField(func(s *Settings) *string { return &s.Url })},
+	{env: "INVENTORY_TIMEOUT", flag: "inventory-timeout", usage: "Timeout of a single attempt, e.g. 1s",
+		set: durationField(func(s *Settings) *Duration { return &s.Timeout })},
+	{env: "INVENTORY_TOTAL_TIMEOUT", flag: "inventory-total-timeout", usage: "Timeout of the whole operation including retries",
+		set: durationField(func(s *Settings) *Duration { return &s.TotalTimeout })},
+	{env: "INVENTORY_LOGGING", flag: "inventory-logging", usage: "Logs every outgoing request",
+		set: boolField(func(s *Settings) *bool { return &s.Logging })},
+	{env: "INVENTORY_CONTENT_TYPE", flag: "inventory-content-type", usage: "Media type of request bodies",
+		set: stringField(func(s *Settings) *string { return &s.ContentType })},
+	{env: "INVENTORY_MAX_RETRIES", flag: "inventory-max-retries", usage: "Maximum number of retries",
+		set: intField(func(s *Settings) *int { return &s.Retries.MaxRetries })},
+	{env: "INVENTORY_RETRY_DELAY", flag: "inventory-retry-delay", usage: "Delay before the first retry, e.g. 100ms",
+		set: durationField(func(s *Settings) *Duration { return &s.Retries.Delay })},
+	{env: "INVENTORY_RETRY_FACTOR", flag: "inventory-retry-factor", usage: "Factor of the exponential backoff",
+		set: floatField(func(s *Settings) *float64 { return &s.Retries.Factor })},
+}
+
+func stringField(field func(*Settings) *string) func(*Settings, string) error {
+	return func(settings *Settings, value string) error {
+		*field(settings) = value
+		return nil
+	}
+}
+
+func durationField(field func(*Settings) *Duration) func(*Settings, string) error {
+	return func(settings *Settings, value string) error {
+		return field(settings).UnmarshalText([]byte(value))
+	}
+}
+
+func boolField(field func(*Settings) *bool) func(*Settings, string) error {
+	return func(settings *Settings, value string) (err error) {
+		*field(settings), err = strconv.ParseBool(value)
+		return err
+	}
+}
+
+func intField(field func(*Settings) *int) func(*Settings, string) error {
+	return func(settings *Settings, value string) (err error) {
+		*field(settings), err = strconv.Atoi(value)
+		return err
+	}
+}
+
+func floatField(field func(*Settings) *float64) func(*Settings, string) error {
+	return func(settings *Settings, value string) (err error) {
+		*field(settings), err = strconv.ParseFloat(value, 64)
+		return err
+	}
+}
+
+// Reads YAML, JSON or TOML file (picked by its extension) into settings, fields missing in the file are left untouched.
+//
+// If the extension is none of .yaml, .yml, .json or .toml it returns UnsupportedConfigFormatError.
+func LoadFile(path string, settings *Settings) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yaml.Unmarshal(content, settings)
+	case ".json":
+		return json.Unmarshal(content, settings)
+	case ".toml":
+		return toml.Unmarshal(content, settings)
+	default:
+		return UnsupportedConfigFormatError
+	}
+}
+
+// Reads INVENTORY_* environment variables into settings, unset variables are skipped.
+//
+// Values which cannot be parsed are reported together as ValidationError with names of the variables.
+func LoadEnv(settings *Settings) error {
+	var errs []*FieldError
+	for _, field := range settingsFields {
+		value, ok := os.LookupEnv(field.env)
+		if !ok {
+			continue
+		}
+		if err := field.set(settings, value); err != nil {
+			errs = append(errs, &FieldError{Field: field.env, Err: err})
+		}
+	}
+	return newValidationError(errs)
+}
+
+// Registers -inventory-* flags on flags, their values are read with LoadFlags once flags are parsed
+func RegisterFlags(flags *flag.FlagSet) {
+	for _, field := range settingsFields {
+		flags.String(field.flag, "", field.usage)
+	}
+}
+
+// Reads flags registered by RegisterFlags into settings, only the flags which were set are applied.
+//
+// Values which cannot be parsed are reported together as ValidationError with names of the flags.
+func LoadFlags(flags *flag.FlagSet, settings *Settings) error {
+	set := map[string]string{}
+	flags.Visit(func(flag *flag.Flag) {
+		set[flag.Name] = flag.Value.String()
+	})
+
+	var errs []*FieldError
+	for _, field := range settingsFields {
+		value, ok := set[field.flag]
+		if !ok {
+			continue
+		}
+		if err := field.set(settings, value); err != nil {
+			errs = append(errs, &FieldError{Field: "-" + field.flag, Err: err})
+		}
+	}
+	return newValidationError(errs)
+}
+
+// Validates all the fields and returns every problem at once as ValidationError
+func (s Settings) Validate() error {
+	var errs []*FieldError
+	if s.Url == "" {
+		errs = append(errs, &FieldError{Field: "url", Err: FieldRequiredError})
+	} else if parsed, err := url.Parse(s.Url); err != nil {
+		errs = append(errs, &FieldError{Field: "url", Err: err})
+	} else if !parsed.IsAbs() || parsed.Host == "" {
+		errs = append(errs, &FieldError{Field: "url", Err: errors.New("has to be an absolute url")})
+	}
+	if time.Duration(s.Timeout).Milliseconds() <= 0 {
+		errs = append(errs, &FieldError{Field: "timeout", Err: http.TimeoutZeroError})
+	}
+	if s.TotalTimeout < 0 {
+		errs = append(errs, &FieldError{Field: "total_timeout", Err: http.TotalTimeoutNegativeError})
+	}
+	if s.Retries.MaxRetries <= 0 {
+		errs = append(errs, &FieldError{Field: "retries.max_retries", Err: retry.MaxRetriesZeroError})
+	}
+	if time.Duration(s.Retries.Delay).Milliseconds() <= 0 {
+		errs = append(errs, &FieldError{Field: "retries.delay", Err: retry.DelayZeroError})
+	}
+	if s.Retries.Factor <= 0 {
+		errs = append(errs, &FieldError{Field: "retries.factor", Err: retry.FactorZeroError})
+	}
+	return newValidationError(errs)
+}
+
+// Validates the settings and turns them into ClientConfig
+func (s Settings) ClientConfig() (ClientConfig, error) {
+	return s.apply(ClientConfig{})
+}
+
+// Validates the settings and sets them on top of config, fields which can't be expressed with Settings are kept
+func (s Settings) apply(config ClientConfig) (ClientConfig, error) {
+	if err := s.Validate(); err != nil {
+		return config, err
+	}
+	parsed, _ := url.Parse(s.Url)
+	config.Timeout = time.Duration(s.Timeout)
+	config.TotalTimeout = time.Duration(s.TotalTimeout)
+	config.Logging = s.Logging
+	config.Url = *parsed
+	config.ContentType = s.ContentType
+	config.RetriesConfig.MaxRetries = s.Retries.MaxRetries
+	config.RetriesConfig.Delay = time.Duration(s.Retries.Delay)
+	config.RetriesConfig.Factor = s.Retries.Factor
+	config.Headers = copyHeaders(s.Headers)
+	return config, nil
+}
+
+// Settings of config, reverse of ClientConfig
+func settingsOf(config ClientConfig) Settings {
+	return Settings{
+		Url:          config.Url.String(),
+		Timeout:      Duration(config.Timeout),
+		TotalTimeout: Duration(config.TotalTimeout),
+		Logging:      config.Logging,
+		ContentType:  config.ContentType,
+		Retries: RetriesSettings{
+			MaxRetries: config.RetriesConfig.MaxRetries,
+			Delay:      Duration(config.RetriesConfig.Delay),
+			Factor:     config.RetriesConfig.Factor,
+		},
+		Headers: copyHeaders(config.Headers),
+	}
+}
+
+// Copies headers, so decoding into Settings never writes into the map of an active config
+func copyHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(headers))
+	for key, value := range headers {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,147 @@
+package inventory
+
+import (
+	"errors"
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"os"
+	"path/filepath"
+	"test2/http"
+	"test2/http/retry"
+	"testing"
+	"time"
+)
+
+var validSettings = Settings{
+	Url:     "https://inventory.example.com/api",
+	Timeout: Duration(time.Second),
+	Retries: RetriesSettings{MaxRetries: 3, Delay: Duration(100 * time.Millisecond), Factor: 2},
+}
+
+func TestLoadFile(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "url: https://inventory.example.com/api\ntimeout: 1s\nretries:\n  max_retries: 3\n  delay: 100ms\n  factor: 2\n",
+		"config.json": `{"url": "https://inventory.example.com/api", "timeout": "1s", "retries": {"max_retries": 3, "delay": "100ms", "factor": 2}}`,
+		"config.toml": "url = \"https://inventory.example.com/api\"\ntimeout = \"1s\"\n[retries]\nmax_retries = 3\ndelay = \"100ms\"\nfactor = 2.0\n",
+	}
+	for name, content := range files {
+		t.Logf("Given %s file", name)
+		path := filepath.Join(t.TempDir(), name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+		t.Logf("When loading it on top of settings with logging enabled")
+		settings := Settings{Logging: true}
+		err := LoadFile(path, &settings)
+
+		t.Logf("Should read all the fields and keep the missing ones")
+		assert.NoError(t, err)
+		expected := validSettings
+		expected.Logging = true
+		assert.Equal(t, expected, settings)
+	}
+}
+
+func TestLoadFileWithUnsupportedFormat(t *testing.T) {
+	t.Logf("Given .ini file")
+	path := filepath.Join(t.TempDir(), "config.ini")
+	assert.NoError(t, os.WriteFile(path, []byte("url=https://inventory.example.com"), 0600))
+
+	t.Logf("When loading it")
+	err := LoadFile(path, &Settings{})
+
+	t.Logf("Should return UnsupportedConfigFormatError")
+	assert.Equal(t, UnsupportedConfigFormatError, err)
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Logf("Given INVENTORY_* environment variables")
+	t.Setenv("INVENTORY_URL", "https://inventory.example.com/api")
+	t.Setenv("INVENTORY_TIMEOUT", "1s")
+	t.Setenv("INVENTORY_MAX_RETRIES", "3")
+	t.Setenv("INVENTORY_RETRY_DELAY", "100ms")
+	t.Setenv("INVENTORY_RETRY_FACTOR", "2")
+
+	t.Logf("When loading them")
+	var settings Settings
+	err := LoadEnv(&settings)
+
+	t.Logf("Should read all of them")
+	assert.NoError(t, err)
+	assert.Equal(t, validSettings, settings)
+}
+
+func TestLoadEnvWithInvalidValues(t *testing.T) {
+	t.Logf("Given INVENTORY_* environment variables with invalid values")
+	t.Setenv("INVENTORY_TIMEOUT", "second")
+	t.Setenv("INVENTORY_MAX_RETRIES", "three")
+	t.Setenv("INVENTORY_LOGGING", "true")
+
+	t.Logf("When loading them")
+	var settings Settings
+	err := LoadEnv(&settings)
+
+	t.Logf("Should report both of the invalid variables and read the valid one")
+	var validationError *ValidationError
+	assert.True(t, errors.As(err, &validationError))
+	assert.Len(t, validationError.Errors, 2)
+	assert.Equal(t, "INVENTORY_TIMEOUT", validationError.Errors[0].Field)
+	assert.Equal(t, "INVENTORY_MAX_RETRIES", validationError.Errors[1].Field)
+	assert.True(t, settings.Logging)
+}
+
+func TestLoadFlags(t *testing.T) {
+	t.Logf("Given flags with timeout and max retries set")
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterFlags(flags)
+	assert.NoError(t, flags.Parse([]string{"-inventory-timeout=2s", "-inventory-max-retries", "5"}))
+
+	t.Logf("When loading them on top of valid settings")
+	settings := validSettings
+	err := LoadFlags(flags, &settings)
+
+	t.Logf("Should override only the flags which were set")
+	assert.NoError(t, err)
+	expected := validSettings
+	expected.Timeout = Duration(2 * time.Second)
+	expected.Retries.MaxRetries = 5
+	assert.Equal(t, expected, settings)
+}
+
+func TestSettings_Validate(t *testing.T) {
+	t.Logf("Given settings with every field invalid")
+	settings := Settings{Url: "/inventory", TotalTimeout: Duration(-time.Second)}
+
+	t.Logf("When validating them")
+	err := settings.Validate()
+
+	t.Logf("Should report every problem with its field path")
+	var validationError *ValidationError
+	assert.True(t, errors.As(err, &validationError))
+	var fields []string
+	for _, fieldError := range validationError.Errors {
+		fields = append(fields, fieldError.Field)
+	}
+	assert.Equal(t, []string{"url", "timeout", "total_timeout", "retries.max_retries", "retries.delay", "retries.factor"}, fields)
+	assert.True(t, errors.Is(err, http.TimeoutZeroError))
+	assert.True(t, errors.Is(err, retry.MaxRetriesZeroError))
+	assert.True(t, errors.Is(err, retry.DelayZeroError))
+	assert.True(t, errors.Is(err, retry.FactorZeroError))
+}
+
+func TestSettings_ClientConfig(t *testing.T) {
+	t.Logf("Given valid settings")
+	settings := validSettings
+
+	t.Logf("When turning them into ClientConfig")
+	config, err := settings.ClientConfig()
+
+	t.Logf("Should return ClientConfig with the same values")
+	assert.NoError(t, err)
+	expectedUrl, _ := url.Parse("https://inventory.example.com/api")
+	assert.Equal(t, ClientConfig{
+		Timeout:       time.Second,
+		Url:           *expectedUrl,
+		RetriesConfig: retry.RetriesConfig{MaxRetries: 3, Delay: 100 * time.Millisecond, Factor: 2},
+	}, config)
+}
@@ -0,0 +1,396 @@
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"test2/clock"
+	"test2/http"
+	"test2/http/retry"
+	"time"
+)
+
+const defaultOutboxInterval = 5 * time.Second
+
+type OutboxStatus string
+
+const (
+	OutboxPending      OutboxStatus = "pending"
+	OutboxDelivered    OutboxStatus = "delivered"
+	OutboxDeadLettered OutboxStatus = "dead_lettered"
+)
+
+type OutboxConfig struct {
+	// Append-only journal of the outbox, created when it doesn't exist
+	Path string
+	// Retries of the delivery of a single entry, used instead of the retries of the Client, so attempts don't multiply.
+	// Entries which are still undeliverable once they're exhausted are retried after Interval
+	Retries retry.RetriesConfig
+	// Delay before retrying an undeliverable entry, defaults to 5s
+	Interval time.Duration
+	// Optional, called for every entry which the service rejected for good
+	OnDeadLetter func(OutboxEntry)
+}
+
+// Snapshot of the Outbox's counters, returned by Outbox.Metrics
+type OutboxMetrics struct {
+	// Number of entries waiting for the delivery
+	Pending int64
+	// Entries delivered or dead-lettered since the Outbox was opened
+	Delivered    int64
+	DeadLettered int64
+	// Number of delivery attempts which failed, including the ones which were retried
+	FailedAttempts int64
+}
+
+// Delivers created items at least once, even across restarts.
+//
+// Every entry is appended to a journal file and synced before CreateItem returns, Run delivers them oldest first
+// with an idempotency key so duplicates caused by redeliveries can be skipped by the service. Entries are retried
+// while the service is unavailable (a network error, a timeout, 5xx or 429 status) and dead-lettered when it
+// rejects them with any other error.
+type Outbox struct {
+	client  *Client
+	config  OutboxConfig
+	retry   *retry.Retry
+	journal *os.File
+
+	mutex   sync.Mutex
+	entries map[int64]*OutboxEntry
+	pending []int64
+	nextId  int64
+	metrics OutboxMetrics
+	closed  bool
+	// Signalled when an entry is added, so an idle Run delivers it right away
+	added chan struct{}
+}
+
+// Record of the journal, one per line
+type journalRecord struct {
+	Entry OutboxEntry `json:"entry"`
+}
+
+// Opens the journal at OutboxConfig.Path and restores its entries, delivered ones are compacted away.
+//
+// If OutboxConfig.Path is empty it returns OutboxPathEmptyError, if a line of the journal other than a torn last one
+// can't be read it returns OutboxJournalError, errors of OutboxConfig.Retries and of reading the journal
+// are returned as they are.
+func OpenOutbox(client *Client, config OutboxConfig) (*Outbox, error) {
+	if config.Path == "" {
+		return nil, OutboxPathEmptyError
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultOutboxInterval
+	}
+	if config.Retries.Clock == nil {
+		config.Retries.Clock = client.Config().Clock
+	}
+	retry, err := retry.NewRetries(config.Retries)
+	if err != nil {
+		return nil, err
+	}
+
+	outbox := &Outbox{client: client, config: config, retry: retry, entries: map[int64]*OutboxEntry{}, added: make(chan struct{}, 1)}
+	if err := outbox.restore(); err != nil {
+		return nil, err
+	}
+	if err := outbox.compact(); err != nil {
+		return nil, err
+	}
+	return outbox, nil
+}
+
+// Persists the entry and returns it, the item is created by Run.
+//
+// Invalid items are rejected with ValidationError, if the Outbox is closed it returns OutboxClosedError,
+// errors of writing the journal are returned as they are.
+func (o *Outbox) CreateItem(createInventory CreateInventory) (OutboxEntry, error) {
+	if err := createInventory.ValidateWith(o.client.Limits()); err != nil {
+		return OutboxEntry{}, err
+	}
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return OutboxEntry{}, err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.closed {
+		return OutboxEntry{}, OutboxClosedError
+	}
+	entry := OutboxEntry{
+		Id:             o.nextId + 1,
+		Create:         createInventory,
+		QueuedAt:       o.now(),
+		IdempotencyKey: key,
+		Status:         OutboxPending,
+	}
+	if err := o.append(entry); err != nil {
+		return OutboxEntry{}, err
+	}
+	o.nextId++
+	o.entries[entry.Id] = &entry
+	o.pending = append(o.pending, entry.Id)
+
+	select {
+	case o.added <- struct{}{}:
+	default:
+	}
+	return entry, nil
+}
+
+// Delivers pending entries until ctx is done, only one Run should be active at a time
+func (o *Outbox) Run(ctx context.Context) {
+	for {
+		entry, ok := o.next()
+		if !ok {
+			select {
+			case <-o.added:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		if !o.deliver(ctx, entry) {
+			select {
+			case <-o.clock().After(o.config.Interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// Returns the entry with the id, false when there's none
+func (o *Outbox) Status(id int64) (OutboxEntry, bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	entry, ok := o.entries[id]
+	if !ok {
+		return OutboxEntry{}, false
+	}
+	return *entry, true
+}
+
+// Returns entries with the status ordered by their ids, delivered ones are kept only until the Outbox is reopened
+func (o *Outbox) Entries(status OutboxStatus) []OutboxEntry {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	var entries []OutboxEntry
+	for id := int64(1); id <= o.nextId; id++ {
+		if entry, ok := o.entries[id]; ok && entry.Status == status {
+			entries = append(entries, *entry)
+		}
+	}
+	return entries
+}
+
+func (o *Outbox) Metrics() OutboxMetrics {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	metrics := o.metrics
+	metrics.Pending = int64(len(o.pending))
+	return metrics
+}
+
+// Closes the journal, entries which are still pending are delivered once the Outbox is reopened
+func (o *Outbox) Close() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+	return o.journal.Close()
+}
+
+func (o *Outbox) next() (OutboxEntry, bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.closed || len(o.pending) == 0 {
+		return OutboxEntry{}, false
+	}
+	return *o.entries[o.pending[0]], true
+}
+
+// Sends the entry with retries, returns false when it's still pending
+func (o *Outbox) deliver(ctx context.Context, entry OutboxEntry) bool {
+	headers := http.Headers{"Idempotency-Key": entry.IdempotencyKey}
+	item, err := retry.Do(ctx, o.retry, isUnavailable, func(ctx context.Context) (Inventory, error) {
+		item, err := o.client.CreateItem(retry.WithMaxRetries(http.WithHeaders(ctx, headers), 0), entry.Create)
+		if err != nil && ctx.Err() == nil {
+			o.failed(entry.Id, err)
+		}
+		return item, err
+	})
+	if ctx.Err() != nil {
+		return false
+	}
+
+	switch {
+	case err == nil:
+		entry.Status = OutboxDelivered
+		entry.Item = &item
+	case isUnavailable(err):
+		return false
+	default:
+		entry.Status = OutboxDeadLettered
+		entry.LastError = err.Error()
+	}
+	if err := o.resolve(entry); err != nil {
+		// The entry stays pending in the journal, so it's delivered again after a restart at worst
+		log.Printf("Inventory outbox failed to record entry [%d]: %s \n", entry.Id, err)
+	}
+	if entry.Status == OutboxDeadLettered && o.config.OnDeadLetter != nil {
+		o.config.OnDeadLetter(entry)
+	}
+	return true
+}
+
+func (o *Outbox) failed(id int64, err error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	entry := o.entries[id]
+	entry.Attempts++
+	entry.LastError = err.Error()
+	o.metrics.FailedAttempts++
+}
+
+// Records the delivered or dead-lettered entry and removes it from the pending ones
+func (o *Outbox) resolve(entry OutboxEntry) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	current := o.entries[entry.Id]
+	entry.Attempts = current.Attempts
+	if entry.Status == OutboxDelivered {
+		entry.LastError = current.LastError
+	}
+	*current = entry
+	o.pending = o.pending[1:]
+	if entry.Status == OutboxDelivered {
+		o.metrics.Delivered++
+	} else {
+		o.metrics.DeadLettered++
+	}
+	if o.closed {
+		return OutboxClosedError
+	}
+	return o.append(entry)
+}
+
+// Appends the latest state of the entry to the journal and syncs it to the disk. A failed write is truncated away,
+// so the next record doesn't continue a partial line
+func (o *Outbox) append(entry OutboxEntry) error {
+	line, err := json.Marshal(journalRecord{Entry: entry})
+	if err != nil {
+		return err
+	}
+	info, err := o.journal.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := o.journal.Write(append(line, '\n')); err != nil {
+		o.journal.Truncate(info.Size())
+		return err
+	}
+	return o.journal.Sync()
+}
+
+// Reads the journal, the latest record of every entry wins. A torn last line left by a crash (one which is not
+// terminated by a new line) is skipped, any other unreadable line is returned as OutboxJournalError
+func (o *Outbox) restore() error {
+	content, err := os.ReadFile(o.config.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 4096), len(content)+1)
+	line, terminated := 0, bytes.Count(content, []byte("\n"))
+	for scanner.Scan() {
+		line++
+		var record journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			if line > terminated {
+				continue
+			}
+			return &OutboxJournalError{Path: o.config.Path, Line: line, Err: err}
+		}
+		entry := record.Entry
+		o.entries[entry.Id] = &entry
+		if entry.Id > o.nextId {
+			o.nextId = entry.Id
+		}
+	}
+	for id := int64(1); id <= o.nextId; id++ {
+		if entry, ok := o.entries[id]; ok && entry.Status == OutboxPending {
+			o.pending = append(o.pending, id)
+		}
+	}
+	return scanner.Err()
+}
+
+// Rewrites the journal with pending and dead-lettered entries only and opens it for appending
+func (o *Outbox) compact() error {
+	var content bytes.Buffer
+	for id := int64(1); id <= o.nextId; id++ {
+		entry, ok := o.entries[id]
+		if !ok {
+			continue
+		}
+		if entry.Status == OutboxDelivered {
+			delete(o.entries, id)
+			continue
+		}
+		line, err := json.Marshal(journalRecord{Entry: *entry})
+		if err != nil {
+			return err
+		}
+		content.Write(append(line, '\n'))
+	}
+
+	temporary, err := os.CreateTemp(filepath.Dir(o.config.Path), filepath.Base(o.config.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(content.Bytes()); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Sync(); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temporary.Name(), o.config.Path); err != nil {
+		return err
+	}
+	o.journal, err = os.OpenFile(o.config.Path, os.O_APPEND|os.O_WRONLY, 0600)
+	return err
+}
+
+func (o *Outbox) now() time.Time {
+	return o.clock().Now()
+}
+
+func (o *Outbox) clock() clock.Clock {
+	return clock.OrSystem(o.client.Config().Clock)
+}
+
+func newIdempotencyKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
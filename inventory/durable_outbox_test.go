@@ -0,0 +1,190 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	corehttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"test2/http/retry"
+	"testing"
+	"time"
+)
+
+// Inventory server creating items once per idempotency key, items named "invalid" are rejected with 422 status
+// and the first failures requests fail with 503 status
+type idempotentServer struct {
+	mutex    sync.Mutex
+	failures int
+	keys     map[string]Inventory
+	created  []string
+}
+
+func (s *idempotentServer) ServeHTTP(res corehttp.ResponseWriter, req *corehttp.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		res.WriteHeader(503)
+		return
+	}
+	var createInventory CreateInventory
+	json.NewDecoder(req.Body).Decode(&createInventory)
+	if createInventory.Name == "invalid" {
+		res.WriteHeader(422)
+		return
+	}
+	key := req.Header.Get("Idempotency-Key")
+	item, ok := s.keys[key]
+	if !ok {
+		item = Inventory{Id: len(s.keys) + 1, Name: createInventory.Name}
+		s.keys[key] = item
+		s.created = append(s.created, createInventory.Name)
+	}
+	res.WriteHeader(201)
+	json.NewEncoder(res).Encode(item)
+}
+
+func (s *idempotentServer) createdNames() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.created...)
+}
+
+var outboxRetries = retry.RetriesConfig{MaxRetries: 2, Delay: time.Millisecond, Factor: 2}
+
+func TestOutbox_DeliversInOrderAfterFailures(t *testing.T) {
+	t.Logf("Given inventory server failing the first 5 requests")
+	idempotent := &idempotentServer{failures: 5, keys: map[string]Inventory{}}
+	server := httptest.NewServer(idempotent)
+	defer server.Close()
+
+	t.Logf("And given Outbox reporting dead letters")
+	var mutex sync.Mutex
+	var deadLetters []OutboxEntry
+	outbox, err := OpenOutbox(newTestClient(t, server), OutboxConfig{
+		Path:     filepath.Join(t.TempDir(), "outbox.jsonl"),
+		Retries:  outboxRetries,
+		Interval: time.Millisecond,
+		OnDeadLetter: func(entry OutboxEntry) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			deadLetters = append(deadLetters, entry)
+		},
+	})
+	assert.NoError(t, err)
+	defer outbox.Close()
+
+	t.Logf("When creating 3 items and running the worker")
+	var entries []OutboxEntry
+	for _, name := range []string{"first", "invalid", "second"} {
+		entry, err := outbox.CreateItem(CreateInventory{Name: name})
+		assert.NoError(t, err)
+		entries = append(entries, entry)
+	}
+	assert.Equal(t, OutboxPending, entries[0].Status)
+	assert.NotEmpty(t, entries[0].IdempotencyKey)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go outbox.Run(ctx)
+	waitFor(t, func() bool { return outbox.Metrics().Pending == 0 })
+
+	t.Logf("Should deliver valid items in order and dead-letter the rejected one")
+	assert.Equal(t, []string{"first", "second"}, idempotent.createdNames())
+	first, _ := outbox.Status(entries[0].Id)
+	assert.Equal(t, OutboxDelivered, first.Status)
+	assert.Equal(t, &Inventory{Id: 1, Name: "first"}, first.Item)
+	assert.Equal(t, 5, first.Attempts, "every failed request should be a single attempt, not retried by the Client")
+	invalid, _ := outbox.Status(entries[1].Id)
+	assert.Equal(t, OutboxDeadLettered, invalid.Status)
+	assert.Equal(t, []OutboxEntry{invalid}, outbox.Entries(OutboxDeadLettered))
+	mutex.Lock()
+	assert.Len(t, deadLetters, 1)
+	mutex.Unlock()
+	metrics := outbox.Metrics()
+	assert.Equal(t, int64(2), metrics.Delivered)
+	assert.Equal(t, int64(1), metrics.DeadLettered)
+	assert.Equal(t, int64(first.Attempts+1), metrics.FailedAttempts)
+}
+
+func TestOutbox_RestoresPendingEntriesAfterRestart(t *testing.T) {
+	t.Logf("Given inventory server")
+	idempotent := &idempotentServer{keys: map[string]Inventory{}}
+	server := httptest.NewServer(idempotent)
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	t.Logf("And given Outbox closed with a pending and a dead-lettered entry")
+	outbox, err := OpenOutbox(newTestClient(t, server), OutboxConfig{Path: path, Retries: outboxRetries})
+	assert.NoError(t, err)
+	invalid, _ := outbox.CreateItem(CreateInventory{Name: "invalid"})
+	ctx, cancel := context.WithCancel(context.Background())
+	go outbox.Run(ctx)
+	waitFor(t, func() bool { return outbox.Metrics().DeadLettered == 1 })
+	cancel()
+	pending, _ := outbox.CreateItem(CreateInventory{Name: "pending"})
+	assert.NoError(t, outbox.Close())
+
+	t.Logf("And given a crash which left a torn line at the end of the journal")
+	journal, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	journal.WriteString(`{"entry":{"id":3,`)
+	journal.Close()
+
+	t.Logf("When reopening the Outbox and running the worker")
+	reopened, err := OpenOutbox(newTestClient(t, server), OutboxConfig{Path: path, Retries: outboxRetries})
+	assert.NoError(t, err)
+	defer reopened.Close()
+	restored, _ := reopened.Status(pending.Id)
+	assert.Equal(t, OutboxPending, restored.Status)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go reopened.Run(ctx)
+	waitFor(t, func() bool { return reopened.Metrics().Pending == 0 })
+
+	t.Logf("Should deliver the pending entry with its idempotency key and keep the dead-lettered one")
+	delivered, _ := reopened.Status(pending.Id)
+	assert.Equal(t, OutboxDelivered, delivered.Status)
+	assert.Equal(t, pending.IdempotencyKey, delivered.IdempotencyKey)
+	assert.Contains(t, idempotent.keys, pending.IdempotencyKey)
+	deadLettered, ok := reopened.Status(invalid.Id)
+	assert.True(t, ok)
+	assert.Equal(t, OutboxDeadLettered, deadLettered.Status)
+
+	t.Logf("And new entries should continue the sequence")
+	next, err := reopened.CreateItem(CreateInventory{Name: "next"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), next.Id)
+}
+
+func TestOpenOutboxWithCorruptedJournal(t *testing.T) {
+	t.Logf("Given journal with a corrupted line followed by a valid one")
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	os.WriteFile(path, []byte("{\"entry\":{\"id\":1,\n{\"entry\":{\"id\":2,\"status\":\"pending\"}}\n"), 0600)
+
+	t.Logf("When opening Outbox")
+	server := httptest.NewServer(&idempotentServer{keys: map[string]Inventory{}})
+	defer server.Close()
+	outbox, err := OpenOutbox(newTestClient(t, server), OutboxConfig{Path: path, Retries: outboxRetries})
+
+	t.Logf("Should return OutboxJournalError with the corrupted line")
+	assert.Nil(t, outbox)
+	var journalError *OutboxJournalError
+	assert.True(t, errors.As(err, &journalError))
+	assert.Equal(t, 1, journalError.Line)
+}
+
+func TestOpenOutboxWithoutPath(t *testing.T) {
+	t.Logf("Given OutboxConfig without path")
+	config := OutboxConfig{Retries: outboxRetries}
+
+	t.Logf("When opening Outbox")
+	outbox, err := OpenOutbox(&Client{}, config)
+
+	t.Logf("Should return OutboxPathEmptyError")
+	assert.Nil(t, outbox)
+	assert.Equal(t, OutboxPathEmptyError, err)
+}
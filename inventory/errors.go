@@ -0,0 +1,116 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Returned by LoadFile when the extension of the file is none of .yaml, .yml, .json or .toml
+var UnsupportedConfigFormatError = errors.New("config file has to be one of yaml, json or toml")
+
+// Returned by NewCachedClient when TTL or MaxStale of CacheConfig is negative
+var CacheConfigNegativeError = errors.New("cache ttl and max stale cannot be negative")
+
+// Errors returned by OpenOutbox and Outbox.CreateItem
+var (
+	OutboxPathEmptyError = errors.New("outbox path cannot be empty")
+	OutboxClosedError    = errors.New("outbox is closed")
+)
+
+// Returned by OpenOutbox when a line of the journal other than a torn last one can't be read
+type OutboxJournalError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *OutboxJournalError) Error() string {
+	return fmt.Sprintf("outbox journal %s is corrupted at line %d: %s", e.Path, e.Line, e.Err)
+}
+
+func (e *OutboxJournalError) Unwrap() error {
+	return e.Err
+}
+
+// Returned by Client.Watch when any of the durations of WatchOptions is negative
+var WatchOptionsNegativeError = errors.New("watch intervals cannot be negative")
+
+// Wrapped by FieldError when a field fails validation
+var (
+	FieldRequiredError     = errors.New("is required")
+	InvalidCharactersError = errors.New("contains control characters or invalid UTF-8")
+	InvalidQuantityError   = errors.New("has to be a finite number not below zero")
+)
+
+// Wrapped by FieldError when a field is longer than the limit
+type TooLongError struct {
+	Max int
+}
+
+func (e *TooLongError) Error() string {
+	return fmt.Sprintf("has to be at most %d characters long", e.Max)
+}
+
+// Wrapped by FieldError when a field has more entries than the limit
+type TooManyError struct {
+	Max int
+}
+
+func (e *TooManyError) Error() string {
+	return fmt.Sprintf("can have at most %d entries", e.Max)
+}
+
+// Problem with a single field, Field is its path, e.g. retries.max_retries
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Aggregates problems of all the fields, so they can be fixed at once, returned for invalid configs and models
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("validation failed: %s", strings.Join(messages, "; "))
+}
+
+// Supports errors.Is and errors.As against any of the field errors
+func (e *ValidationError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *ValidationError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns nil when there are no errors
+func newValidationError(errs []*FieldError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
@@ -1,12 +1,197 @@
 package inventory
 
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/vmihailenco/msgpack/v5"
+	"reflect"
+	"strings"
+	"test2/http"
+	"time"
+)
+
 type Inventory struct {
-	Id          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Id          int    `json:"id" xml:"id"`
+	Name        string `json:"name" xml:"name"`
+	Description string `json:"description" xml:"description"`
+	// Stock keeping unit, unique within the service
+	Sku      string  `json:"sku,omitempty" xml:"sku,omitempty"`
+	Quantity float64 `json:"quantity" xml:"quantity"`
+	// Unit of Quantity, e.g. pcs or kg
+	Unit     string    `json:"unit,omitempty" xml:"unit,omitempty"`
+	Location *Location `json:"location,omitempty" xml:"location,omitempty"`
+	// Set by the service, zero timestamps are omitted when encoding
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+	// Incremented by the service on every change
+	Version int64 `json:"version,omitempty" xml:"version,omitempty"`
+	// Free-form attributes, not supported by the xml codec
+	Attributes map[string]interface{} `json:"attributes,omitempty" xml:"-"`
+	// Fields of the service unknown to this client, kept by the JSON and MessagePack codecs so sending the item back
+	// with UpdateItem doesn't drop them. The XML codec doesn't support them, they're dropped along with Attributes
+	Unknown map[string]interface{} `json:"-" xml:"-"`
 }
 
+// Item created by the caller, it carries only the fields known to the client as there's nothing to keep
 type CreateInventory struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name        string                 `json:"name" xml:"name"`
+	Description string                 `json:"description" xml:"description"`
+	Sku         string                 `json:"sku,omitempty" xml:"sku,omitempty"`
+	Quantity    float64                `json:"quantity,omitempty" xml:"quantity,omitempty"`
+	Unit        string                 `json:"unit,omitempty" xml:"unit,omitempty"`
+	Location    *Location              `json:"location,omitempty" xml:"location,omitempty"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty" xml:"-"`
+}
+
+// Where the item is stored, only Warehouse is required by the service
+type Location struct {
+	Warehouse string `json:"warehouse" xml:"warehouse"`
+	Zone      string `json:"zone,omitempty" xml:"zone,omitempty"`
+	Bin       string `json:"bin,omitempty" xml:"bin,omitempty"`
+}
+
+// Reports whether the items are the same, timestamps are compared with time.Time.Equal
+func (i Inventory) Equal(other Inventory) bool {
+	if !i.CreatedAt.Equal(other.CreatedAt) || !i.UpdatedAt.Equal(other.UpdatedAt) {
+		return false
+	}
+	i.CreatedAt, i.UpdatedAt = time.Time{}, time.Time{}
+	other.CreatedAt, other.UpdatedAt = time.Time{}, time.Time{}
+	return reflect.DeepEqual(i, other)
+}
+
+// Inventory without its custom encoding, used to encode and decode the known fields
+type knownInventory Inventory
+
+// Known fields are decoded as usual, the rest end up in Unknown with numbers kept as json.Number
+func (i *Inventory) UnmarshalJSON(data []byte) error {
+	var item knownInventory
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return err
+	}
+	*i = Inventory(item)
+	i.Unknown = unknownFields(fields)
+	return nil
+}
+
+// Encodes the known fields along with Unknown ones, known fields take precedence
+func (i Inventory) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(knownInventory(i))
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	i.complete(fields, func(value interface{}) interface{} { return value })
+	return json.Marshal(fields)
+}
+
+// Works the same way as UnmarshalJSON for http.MsgpackCodec
+func (i *Inventory) DecodeMsgpack(decoder *msgpack.Decoder) error {
+	data, err := decoder.DecodeRaw()
+	if err != nil {
+		return err
+	}
+	var item knownInventory
+	if err := (http.MsgpackCodec{}).Unmarshal(data, &item); err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	if err := (http.MsgpackCodec{}).Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*i = Inventory(item)
+	i.Unknown = unknownFields(fields)
+	return nil
+}
+
+// Works the same way as MarshalJSON for http.MsgpackCodec
+func (i Inventory) EncodeMsgpack(encoder *msgpack.Encoder) error {
+	data, err := (http.MsgpackCodec{}).Marshal(knownInventory(i))
+	if err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	if err := (http.MsgpackCodec{}).Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	i.complete(fields, msgpackValue)
+	return encoder.Encode(fields)
+}
+
+// Drops zero timestamps from the encoded known fields and adds the unknown ones converted for the codec
+func (i Inventory) complete(fields map[string]interface{}, convert func(interface{}) interface{}) {
+	if i.CreatedAt.IsZero() {
+		delete(fields, "created_at")
+	}
+	if i.UpdatedAt.IsZero() {
+		delete(fields, "updated_at")
+	}
+	for name, value := range i.Unknown {
+		if _, ok := fields[name]; !ok && !inventoryFields[name] {
+			fields[name] = convert(value)
+		}
+	}
+}
+
+func unknownFields(fields map[string]interface{}) map[string]interface{} {
+	for name := range fields {
+		if inventoryFields[name] {
+			delete(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// Numbers of items decoded from JSON are kept as json.Number, MessagePack needs them as numbers
+func msgpackValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case json.Number:
+		if number, err := typed.Int64(); err == nil {
+			return number
+		}
+		number, _ := typed.Float64()
+		return number
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(typed))
+		for key, nested := range typed {
+			converted[key] = msgpackValue(nested)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(typed))
+		for index, nested := range typed {
+			converted[index] = msgpackValue(nested)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// Json names of the fields of Inventory
+var inventoryFields = jsonFields(reflect.TypeOf(Inventory{}))
+
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
 }
@@ -0,0 +1,104 @@
+package inventory
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"test2/http"
+	"testing"
+	"time"
+)
+
+func TestInventory_JsonRoundTripKeepsUnknownFields(t *testing.T) {
+	t.Logf("Given item of the service with fields unknown to the client")
+	data := `{"id":7,"name":"Hammer","description":"","sku":"HM-1","quantity":2.5,"unit":"kg",` +
+		`"location":{"warehouse":"WAW","bin":"A1"},"created_at":"2026-01-02T03:04:05Z","updated_at":"2026-01-03T03:04:05Z",` +
+		`"version":3,"attributes":{"color":"red","weight":1.2},"supplier":{"id":9},"tags":["tools"]}`
+
+	t.Logf("When decoding it, changing the quantity and encoding it again")
+	var item Inventory
+	err := json.Unmarshal([]byte(data), &item)
+	assert.NoError(t, err)
+	item.Quantity = 3
+	encoded, err := json.Marshal(item)
+	assert.NoError(t, err)
+
+	t.Logf("Should decode the known fields and encode the unknown ones unchanged")
+	assert.Equal(t, Location{Warehouse: "WAW", Bin: "A1"}, *item.Location)
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), item.CreatedAt)
+	assert.Equal(t, int64(3), item.Version)
+	assert.Equal(t, map[string]interface{}{"color": "red", "weight": 1.2}, item.Attributes)
+	assert.Equal(t, map[string]interface{}{"supplier": map[string]interface{}{"id": json.Number("9")}, "tags": []interface{}{"tools"}}, item.Unknown)
+	var expected, actual map[string]interface{}
+	json.Unmarshal([]byte(data), &expected)
+	json.Unmarshal(encoded, &actual)
+	expected["quantity"] = 3.0
+	assert.Equal(t, expected, actual)
+}
+
+func TestInventory_MsgpackRoundTripKeepsUnknownFields(t *testing.T) {
+	t.Logf("Given item decoded from JSON with fields unknown to the client")
+	var item Inventory
+	err := json.Unmarshal([]byte(`{"id":7,"name":"Hammer","quantity":2.5,"created_at":"2026-01-02T03:04:05Z",`+
+		`"supplier":{"id":9,"rating":4.5},"tags":["tools"]}`), &item)
+	assert.NoError(t, err)
+
+	t.Logf("When encoding it with MessagePack and decoding it again")
+	encoded, err := http.MsgpackCodec{}.Marshal(item)
+	assert.NoError(t, err)
+	var decoded Inventory
+	err = http.MsgpackCodec{}.Unmarshal(encoded, &decoded)
+
+	t.Logf("Should keep the known and the unknown fields")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, decoded.Id)
+	assert.Equal(t, 2.5, decoded.Quantity)
+	assert.True(t, decoded.CreatedAt.Equal(item.CreatedAt))
+	assert.True(t, decoded.UpdatedAt.IsZero())
+	assert.Equal(t, map[string]interface{}{
+		"supplier": map[string]interface{}{"id": int64(9), "rating": 4.5},
+		"tags":     []interface{}{"tools"},
+	}, decoded.Unknown)
+}
+
+func TestInventory_XmlDropsUnknownFields(t *testing.T) {
+	t.Logf("Given item with fields unknown to the client")
+	item := Inventory{Id: 7, Name: "Hammer", Unknown: map[string]interface{}{"supplier": "ACME"}}
+
+	t.Logf("When encoding it with XML and decoding it again")
+	encoded, err := http.XMLCodec{}.Marshal(item)
+	assert.NoError(t, err)
+	var decoded Inventory
+	err = http.XMLCodec{}.Unmarshal(encoded, &decoded)
+
+	t.Logf("Should keep only the known fields as XML doesn't support the unknown ones")
+	assert.NoError(t, err)
+	assert.Equal(t, "Hammer", decoded.Name)
+	assert.NotContains(t, string(encoded), "ACME")
+	assert.Nil(t, decoded.Unknown)
+}
+
+func TestInventory_MarshalJSONOmitsZeroTimestamps(t *testing.T) {
+	t.Logf("Given item without timestamps")
+	item := Inventory{Id: 1, Name: "Hammer"}
+
+	t.Logf("When encoding it")
+	encoded, err := json.Marshal(item)
+
+	t.Logf("Should omit the timestamps")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"name":"Hammer","description":"","quantity":0}`, string(encoded))
+}
+
+func TestInventory_Equal(t *testing.T) {
+	t.Logf("Given the same item with timestamps in different time zones")
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	item := Inventory{Id: 1, CreatedAt: createdAt, Attributes: map[string]interface{}{"color": "red"}}
+	other := Inventory{Id: 1, CreatedAt: createdAt.In(time.FixedZone("CET", 3600)), Attributes: map[string]interface{}{"color": "red"}}
+
+	t.Logf("When comparing them")
+
+	t.Logf("Should be equal unless any other field differs")
+	assert.True(t, item.Equal(other))
+	other.Attributes = map[string]interface{}{"color": "blue"}
+	assert.False(t, item.Equal(other))
+}
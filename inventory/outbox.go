@@ -0,0 +1,76 @@
+package inventory
+
+import (
+	"sync"
+	"time"
+)
+
+// Write queued by CachedClient while the service was unavailable or by Outbox before it's delivered
+type OutboxEntry struct {
+	// Sequence number of the entry, increasing in the order of writes
+	Id       int64           `json:"id"`
+	Create   CreateInventory `json:"create"`
+	QueuedAt time.Time       `json:"queued_at"`
+	// Sent as Idempotency-Key with every delivery attempt, so the service can skip duplicates
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// Fields below are only tracked by Outbox
+
+	Status OutboxStatus `json:"status,omitempty"`
+	// Failed delivery attempts, LastError is the error of the last one
+	Attempts  int    `json:"attempts,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+	// Created item, set once the entry is delivered
+	Item *Inventory `json:"item,omitempty"`
+}
+
+// Queued write which the service rejected during the replay
+type Conflict struct {
+	Entry OutboxEntry
+	Err   error
+}
+
+// Queue of writes replayed in order, only one replay runs at a time
+type outbox struct {
+	mutex     sync.Mutex
+	queue     []OutboxEntry
+	nextId    int64
+	replaying sync.Mutex
+}
+
+func (o *outbox) push(create CreateInventory, idempotencyKey string, now time.Time) *OutboxEntry {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.nextId++
+	entry := OutboxEntry{Id: o.nextId, Create: create, QueuedAt: now, IdempotencyKey: idempotencyKey}
+	o.queue = append(o.queue, entry)
+	return &entry
+}
+
+func (o *outbox) entries() []OutboxEntry {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return append([]OutboxEntry{}, o.queue...)
+}
+
+// Sends entries oldest first and removes the ones for which send returns nil, stops at the first error
+func (o *outbox) replay(send func(OutboxEntry) error) error {
+	o.replaying.Lock()
+	defer o.replaying.Unlock()
+	for {
+		o.mutex.Lock()
+		if len(o.queue) == 0 {
+			o.mutex.Unlock()
+			return nil
+		}
+		entry := o.queue[0]
+		o.mutex.Unlock()
+
+		if err := send(entry); err != nil {
+			return err
+		}
+		o.mutex.Lock()
+		o.queue = o.queue[1:]
+		o.mutex.Unlock()
+	}
+}
@@ -0,0 +1,143 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"test2/http"
+	"test2/http/retry"
+	"time"
+)
+
+const defaultWatchInterval = 5 * time.Second
+
+// Replaces the active config, requests which are already in flight finish with the previous one.
+// Limits fetched by RefreshLimits are kept unless Url or Endpoints change, then ClientConfig.Limits of the new config
+// are used until they are fetched again.
+// The connection pool, metrics and endpoints of the http.Client are carried over, see http.Client.Reconfigure.
+//
+// The config is validated the same way as by NewClient, invalid configs are rejected with the error
+// and the previous config stays active. Both accepted and rejected updates are logged.
+func (c *Client) Update(config ClientConfig) error {
+	c.updateMutex.Lock()
+	defer c.updateMutex.Unlock()
+
+	previous := c.current()
+	state, err := newClientState(config, previous.client)
+	if err != nil {
+		log.Printf("Inventory client config update rejected: %s \n", err)
+		return err
+	}
+	c.state.Store(state)
+	if previous.config.Url.String() != config.Url.String() || !reflect.DeepEqual(previous.config.Endpoints, config.Endpoints) {
+		c.limits.Store((*Limits)(nil))
+	}
+	previous.client.Close()
+	log.Printf("Inventory client config updated: %s \n", describeChanges(previous.config, config))
+	return nil
+}
+
+// Polls the file every interval (defaults to 5s) until ctx is done and applies its Settings with Update
+// whenever its content changes, fields which are missing in the file keep their current values.
+// Headers of the file replace all of the current headers.
+//
+// Invalid files are logged and skipped, the current content of the file is treated as already applied.
+func (c *Client) WatchFile(ctx context.Context, path string, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	applied, _ := os.ReadFile(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil || bytes.Equal(content, applied) {
+			continue
+		}
+		applied = content
+		if err := c.reloadFile(path); err != nil {
+			log.Printf("Inventory client config file [%s] rejected: %s \n", path, err)
+		}
+	}
+}
+
+func (c *Client) reloadFile(path string) error {
+	current := c.Config()
+	settings := settingsOf(current)
+	// Headers of the file replace the current ones instead of being merged into them, so they can be removed
+	headers := settings.Headers
+	settings.Headers = nil
+	if err := LoadFile(path, &settings); err != nil {
+		return err
+	}
+	if settings.Headers == nil {
+		settings.Headers = headers
+	}
+	config, err := settings.apply(current)
+	if err != nil {
+		return err
+	}
+	return c.Update(config)
+}
+
+// Lists fields which differ between the configs, e.g. "Timeout: 1s -> 2s". Only simple values are logged,
+// headers are logged without values and urls without passwords
+func describeChanges(previous ClientConfig, next ClientConfig) string {
+	var changes []string
+	previousValue := reflect.ValueOf(previous)
+	nextValue := reflect.ValueOf(next)
+	for i := 0; i < previousValue.NumField(); i++ {
+		before := previousValue.Field(i).Interface()
+		after := nextValue.Field(i).Interface()
+		if !equalSettings(before, after) {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", previousValue.Type().Field(i).Name, describe(before), describe(after)))
+		}
+	}
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	return strings.Join(changes, ", ")
+}
+
+// Retries are compared by what describe logs, their hooks are funcs which never equal each other
+func equalSettings(before interface{}, after interface{}) bool {
+	if previous, ok := before.(retry.RetriesConfig); ok {
+		next := after.(retry.RetriesConfig)
+		return previous.MaxRetries == next.MaxRetries && previous.Delay == next.Delay && previous.Factor == next.Factor
+	}
+	return reflect.DeepEqual(before, after)
+}
+
+func describe(value interface{}) string {
+	switch typed := value.(type) {
+	case url.URL:
+		return typed.Redacted()
+	case http.Headers:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return fmt.Sprintf("%v", keys)
+	case retry.RetriesConfig:
+		return fmt.Sprintf("{MaxRetries:%d Delay:%s Factor:%g}", typed.MaxRetries, typed.Delay, typed.Factor)
+	case time.Duration, bool, string, Limits:
+		return fmt.Sprintf("%+v", value)
+	default:
+		// Transport, endpoints and the others can hold certificates or credentials
+		return "(changed)"
+	}
+}
@@ -0,0 +1,233 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	corehttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"test2/http"
+	"test2/http/retry"
+	"testing"
+	"time"
+)
+
+func TestClient_Update(t *testing.T) {
+	t.Logf("Given two inventory servers")
+	first := httptest.NewServer(itemsRequestHandler("first"))
+	defer first.Close()
+	second := httptest.NewServer(itemsRequestHandler("second"))
+	defer second.Close()
+
+	t.Logf("And given Client of the first one")
+	client := newTestClient(t, first)
+
+	t.Logf("When updating Client's url to the second one")
+	config := client.Config()
+	secondUrl, _ := url.Parse(second.URL)
+	config.Url = *secondUrl
+	err := client.Update(config)
+
+	t.Logf("Should send next requests to the second server")
+	assert.NoError(t, err)
+	items, err := client.GetItems(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "second", items[0].Name)
+	assert.Equal(t, first.URL, client.Url.String())
+	assert.Equal(t, client.Config().Url, *secondUrl)
+	assert.NotNil(t, client.Client)
+	assert.NotNil(t, client.HttpClient())
+}
+
+func TestClient_UpdateKeepsMetrics(t *testing.T) {
+	t.Logf("Given inventory server")
+	server := httptest.NewServer(itemsRequestHandler("first"))
+	defer server.Close()
+
+	t.Logf("And given Client which sent a request")
+	client := newTestClient(t, server)
+	_, err := client.GetItems(context.Background())
+	assert.NoError(t, err)
+
+	t.Logf("When updating Client's timeout and sending another request")
+	config := client.Config()
+	config.Timeout = 2 * time.Second
+	assert.NoError(t, client.Update(config))
+	_, err = client.GetItems(context.Background())
+
+	t.Logf("Should count both requests under their route")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), client.HttpClient().Metrics().Requests)
+	assert.Equal(t, map[string]http.RouteMetrics{itemsRoute: {Requests: 2}}, client.HttpClient().Metrics().Routes)
+}
+
+func TestClient_UpdateWithInvalidConfig(t *testing.T) {
+	t.Logf("Given inventory server")
+	server := httptest.NewServer(itemsRequestHandler("first"))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When updating Client with zero timeout")
+	config := client.Config()
+	config.Timeout = 0
+	err := client.Update(config)
+
+	t.Logf("Should reject the update and keep the previous config")
+	assert.Equal(t, http.TimeoutZeroError, err)
+	assert.Equal(t, time.Second, client.Config().Timeout)
+	items, err := client.GetItems(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "first", items[0].Name)
+}
+
+func TestClient_UpdateDuringRequests(t *testing.T) {
+	t.Logf("Given inventory server")
+	server := httptest.NewServer(itemsRequestHandler("first"))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When updating Client's retries while requests are in flight")
+	var wait sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 4; i++ {
+		wait.Add(2)
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 5; j++ {
+				_, err := client.GetItems(context.Background())
+				errs <- err
+			}
+		}()
+		go func(maxRetries int) {
+			defer wait.Done()
+			config := client.Config()
+			config.RetriesConfig.MaxRetries = maxRetries
+			errs <- client.Update(config)
+		}(i + 1)
+	}
+	wait.Wait()
+	close(errs)
+
+	t.Logf("Should finish all of them without errors")
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestClient_WatchFile(t *testing.T) {
+	t.Logf("Given two inventory servers")
+	first := httptest.NewServer(itemsRequestHandler("first"))
+	defer first.Close()
+	second := httptest.NewServer(itemsRequestHandler("second"))
+	defer second.Close()
+
+	t.Logf("And given Client of the first one watching a config file")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("timeout: 1s\n"), 0600))
+	client := newTestClient(t, first)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.WatchFile(ctx, path, 5*time.Millisecond)
+
+	t.Logf("When the file changes to an invalid timeout and then to the url of the second server")
+	assert.NoError(t, os.WriteFile(path, []byte("timeout: 0s\n"), 0600))
+	time.Sleep(50 * time.Millisecond)
+	timeoutAfterInvalidUpdate := client.Config().Timeout
+	assert.NoError(t, os.WriteFile(path, []byte("url: "+second.URL+"\nheaders:\n  X-Region: eu\n"), 0600))
+	waitFor(t, func() bool {
+		config := client.Config()
+		return config.Url.String() == second.URL
+	})
+
+	t.Logf("Should skip the invalid content and apply the valid one on top of the current config")
+	assert.Equal(t, time.Second, timeoutAfterInvalidUpdate)
+	assert.Equal(t, time.Second, client.Config().Timeout)
+	assert.Equal(t, http.Headers{"X-Region": "eu"}, client.Config().Headers)
+	items, err := client.GetItems(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "second", items[0].Name)
+}
+
+func TestClient_ReloadFileWithHeaders(t *testing.T) {
+	t.Logf("Given inventory server")
+	server := httptest.NewServer(itemsRequestHandler("first"))
+	defer server.Close()
+
+	t.Logf("And given Client with headers")
+	client := newTestClient(t, server)
+	config := client.Config()
+	config.Headers = http.Headers{"X-Region": "eu", "X-Team": "stock"}
+	assert.NoError(t, client.Update(config))
+
+	t.Logf("When reloading a file with a new header and invalid retries")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("retries:\n  max_retries: -1\nheaders:\n  X-Evil: 1\n"), 0600))
+	rejectedErr := client.reloadFile(path)
+	headersAfterRejected := client.Config().Headers
+
+	t.Logf("And when reloading a file with only one of the headers")
+	assert.NoError(t, os.WriteFile(path, []byte("headers:\n  X-Region: us\n"), 0600))
+	acceptedErr := client.reloadFile(path)
+
+	t.Logf("Should keep the headers of the rejected file out of the active config and replace them with the accepted one")
+	assert.Error(t, rejectedErr)
+	assert.Equal(t, http.Headers{"X-Region": "eu", "X-Team": "stock"}, headersAfterRejected)
+	assert.NoError(t, acceptedErr)
+	assert.Equal(t, http.Headers{"X-Region": "us"}, client.Config().Headers)
+}
+
+func TestDescribeChanges(t *testing.T) {
+	t.Logf("Given two configs with different timeouts, retries and headers")
+	previous := ClientConfig{Timeout: time.Second, RetriesConfig: retry.RetriesConfig{MaxRetries: 1}}
+	next := ClientConfig{
+		Timeout:       2 * time.Second,
+		RetriesConfig: retry.RetriesConfig{MaxRetries: 3},
+		Headers:       http.Headers{"Authorization": "secret"},
+	}
+
+	t.Logf("When describing changes between them")
+	changes := describeChanges(previous, next)
+
+	t.Logf("Should list them without values of the headers")
+	assert.Equal(t, "Timeout: 1s -> 2s, RetriesConfig: {MaxRetries:1 Delay:0s Factor:0} -> {MaxRetries:3 Delay:0s Factor:0}, "+
+		"Headers: [] -> [Authorization]", changes)
+}
+
+func itemsRequestHandler(name string) corehttp.HandlerFunc {
+	return func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		json.NewEncoder(res).Encode([]Inventory{{Id: 1, Name: name}})
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDescribeChangesWithRetryHooks(t *testing.T) {
+	t.Logf("Given two configs with the same retries and hooks")
+	retries := func() retry.RetriesConfig {
+		return retry.RetriesConfig{MaxRetries: 3, Delay: time.Millisecond, Factor: 2, OnRetry: func(retry.Attempt) {}}
+	}
+	previous := ClientConfig{Timeout: time.Second, RetriesConfig: retries()}
+	next := ClientConfig{Timeout: time.Second, RetriesConfig: retries()}
+
+	t.Logf("When describing changes between them")
+	changes := describeChanges(previous, next)
+
+	t.Logf("Should not report the retries as changed")
+	assert.Equal(t, "no changes", changes)
+}
@@ -0,0 +1,125 @@
+package inventory
+
+import (
+	"context"
+	"math"
+	"strings"
+	"test2/http"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits of the write models published by the service, zero fields are not enforced
+type Limits struct {
+	MaxNameLength        int `json:"max_name_length" xml:"max_name_length"`
+	MaxDescriptionLength int `json:"max_description_length" xml:"max_description_length"`
+	MaxSkuLength         int `json:"max_sku_length" xml:"max_sku_length"`
+	MaxUnitLength        int `json:"max_unit_length" xml:"max_unit_length"`
+	MaxAttributes        int `json:"max_attributes" xml:"max_attributes"`
+}
+
+// Validates the item without any Limits, see ValidateWith
+func (c CreateInventory) Validate() error {
+	return c.ValidateWith(Limits{})
+}
+
+// Returns ValidationError listing every invalid field: name and warehouse of the location are required,
+// text fields have to be valid UTF-8 without control characters (description may contain new lines and tabs),
+// quantity has to be a finite number not below zero and text fields and attributes can't exceed the non-zero limits
+func (c CreateInventory) ValidateWith(limits Limits) error {
+	return validateItem(c.Name, c.Description, c.Sku, c.Unit, c.Quantity, c.Location, c.Attributes, limits)
+}
+
+// Validates the item without any Limits, see ValidateWith
+func (i Inventory) Validate() error {
+	return i.ValidateWith(Limits{})
+}
+
+// Validates the fields sent by UpdateItem the same way as CreateInventory.ValidateWith,
+// fields set by the service and Unknown ones are not validated
+func (i Inventory) ValidateWith(limits Limits) error {
+	return validateItem(i.Name, i.Description, i.Sku, i.Unit, i.Quantity, i.Location, i.Attributes, limits)
+}
+
+func validateItem(name, description, sku, unit string, quantity float64, location *Location, attributes map[string]interface{}, limits Limits) error {
+	errs := []*FieldError{
+		validateText("name", name, true, limits.MaxNameLength, invalidLineRune),
+		validateText("description", description, false, limits.MaxDescriptionLength, invalidTextRune),
+		validateText("sku", sku, false, limits.MaxSkuLength, invalidLineRune),
+		validateText("unit", unit, false, limits.MaxUnitLength, invalidLineRune),
+	}
+	if !(quantity >= 0) || math.IsInf(quantity, 0) {
+		errs = append(errs, &FieldError{Field: "quantity", Err: InvalidQuantityError})
+	}
+	if location != nil {
+		errs = append(errs,
+			validateText("location.warehouse", location.Warehouse, true, 0, invalidLineRune),
+			validateText("location.zone", location.Zone, false, 0, invalidLineRune),
+			validateText("location.bin", location.Bin, false, 0, invalidLineRune),
+		)
+	}
+	if limits.MaxAttributes > 0 && len(attributes) > limits.MaxAttributes {
+		errs = append(errs, &FieldError{Field: "attributes", Err: &TooManyError{Max: limits.MaxAttributes}})
+	}
+
+	var invalid []*FieldError
+	for _, err := range errs {
+		if err != nil {
+			invalid = append(invalid, err)
+		}
+	}
+	return newValidationError(invalid)
+}
+
+// Returns the first problem of the text field or nil when it's valid, max of zero is not enforced
+func validateText(field string, value string, required bool, max int, invalidRune func(rune) bool) *FieldError {
+	switch {
+	case required && strings.TrimSpace(value) == "":
+		return &FieldError{Field: field, Err: FieldRequiredError}
+	case !utf8.ValidString(value) || strings.IndexFunc(value, invalidRune) >= 0:
+		return &FieldError{Field: field, Err: InvalidCharactersError}
+	case max > 0 && utf8.RuneCountInString(value) > max:
+		return &FieldError{Field: field, Err: &TooLongError{Max: max}}
+	}
+	return nil
+}
+
+// Single line fields can't contain any control characters
+func invalidLineRune(r rune) bool {
+	return unicode.IsControl(r)
+}
+
+// Multi-line fields can contain new lines and tabs
+func invalidTextRune(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+}
+
+// Limits used to validate items before they are sent: the ones fetched by RefreshLimits from the current service,
+// otherwise ClientConfig.Limits
+func (c *Client) Limits() Limits {
+	if limits, ok := c.limits.Load().(*Limits); ok && limits != nil {
+		return *limits
+	}
+	return c.current().config.Limits
+}
+
+// Fetches the limits from the metadata of the service, they take precedence over ClientConfig.Limits
+// until Update changes Url or Endpoints
+func (c *Client) RefreshLimits(ctx context.Context) (Limits, error) {
+	state := c.current()
+	path, err := state.route(metadataRoute, nil)
+	if err != nil {
+		return Limits{}, err
+	}
+	metadata, _, err := http.Get[Metadata](http.WithRoute(ctx, path.Route), state.client, path.String())
+	if err != nil {
+		return Limits{}, err
+	}
+	c.limits.Store(&metadata.Limits)
+	return metadata.Limits, nil
+}
+
+// Metadata of the service returned by metadataRoute
+type Metadata struct {
+	Limits Limits `json:"limits" xml:"limits"`
+}
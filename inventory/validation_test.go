@@ -0,0 +1,168 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"math"
+	corehttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCreateInventory_Validate(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		Item           CreateInventory
+		ExpectedFields map[string]error
+	}{
+		{Name: "valid item", Item: CreateInventory{Name: "<Zażółć> 2x4 (Oak)", Description: strings.Repeat("a", 5000)}},
+		{Name: "blank name", Item: CreateInventory{Name: "  "}, ExpectedFields: map[string]error{"name": FieldRequiredError}},
+		{Name: "item with every field", Item: CreateInventory{Name: "Hammer", Description: "Steel\r\n\thead", Sku: "HM 1", Quantity: 2.5, Unit: "k g", Location: &Location{Warehouse: "WAW 1", Bin: "A-1"}, Attributes: manyAttributes(100)}},
+		{Name: "control characters and invalid UTF-8", Item: CreateInventory{Name: "Ham\x00mer", Description: "a\x07", Sku: "HM\n1", Unit: "\xff", Location: &Location{Warehouse: "WAW", Bin: "A\t1"}}, ExpectedFields: map[string]error{
+			"name":         InvalidCharactersError,
+			"description":  InvalidCharactersError,
+			"sku":          InvalidCharactersError,
+			"unit":         InvalidCharactersError,
+			"location.bin": InvalidCharactersError,
+		}},
+		{Name: "negative quantity", Item: CreateInventory{Name: "Hammer", Quantity: -1}, ExpectedFields: map[string]error{"quantity": InvalidQuantityError}},
+		{Name: "NaN quantity", Item: CreateInventory{Name: "Hammer", Quantity: math.NaN()}, ExpectedFields: map[string]error{"quantity": InvalidQuantityError}},
+		{Name: "infinite quantity", Item: CreateInventory{Name: "Hammer", Quantity: math.Inf(1)}, ExpectedFields: map[string]error{"quantity": InvalidQuantityError}},
+		{Name: "location without warehouse", Item: CreateInventory{Location: &Location{Bin: "A1"}}, ExpectedFields: map[string]error{
+			"name":               FieldRequiredError,
+			"location.warehouse": FieldRequiredError,
+		}},
+	}
+	for _, testCase := range testCases {
+		t.Logf("Given %s", testCase.Name)
+
+		t.Logf("When validating it without limits")
+		err := testCase.Item.Validate()
+
+		t.Logf("Should check the required fields, characters and quantity and return field errors of %v", testCase.ExpectedFields)
+		assertFieldErrors(t, testCase.ExpectedFields, err)
+	}
+}
+
+func TestCreateInventory_ValidateWithLimits(t *testing.T) {
+	t.Logf("Given item exceeding some of the limits")
+	item := CreateInventory{Name: "Hammer", Description: "Ząb", Sku: "HM-1", Unit: "pcs", Attributes: manyAttributes(3)}
+
+	t.Logf("When validating it with the limits")
+	err := item.ValidateWith(Limits{MaxNameLength: 5, MaxDescriptionLength: 3, MaxSkuLength: 3, MaxAttributes: 2})
+
+	t.Logf("Should reject only the fields exceeding non-zero limits")
+	assertFieldErrors(t, map[string]error{
+		"name":       &TooLongError{Max: 5},
+		"sku":        &TooLongError{Max: 3},
+		"attributes": &TooManyError{Max: 2},
+	}, err)
+}
+
+func TestInventory_ValidateWithLimits(t *testing.T) {
+	t.Logf("Given item without name and with too long unit")
+	item := Inventory{Id: 1, Unit: "pieces", Unknown: map[string]interface{}{"supplier": "ACME"}}
+
+	t.Logf("When validating it with the limits")
+	err := item.ValidateWith(Limits{MaxUnitLength: 3})
+
+	t.Logf("Should reject the name and the unit")
+	assertFieldErrors(t, map[string]error{"name": FieldRequiredError, "unit": &TooLongError{Max: 3}}, err)
+}
+
+func TestClient_CreateItemWithInvalidItem(t *testing.T) {
+	t.Logf("Given inventory server counting requests")
+	var requests int64
+	server := httptest.NewServer(corehttp.HandlerFunc(func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		atomic.AddInt64(&requests, 1)
+		res.WriteHeader(201)
+		json.NewEncoder(res).Encode(Inventory{Id: 1})
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When creating item without name")
+	_, err := client.CreateItem(context.Background(), CreateInventory{Description: "no name"})
+
+	t.Logf("Should return ValidationError without sending the request")
+	assert.True(t, errors.Is(err, FieldRequiredError))
+	assert.Equal(t, int64(0), atomic.LoadInt64(&requests))
+}
+
+func TestClient_RefreshLimits(t *testing.T) {
+	t.Logf("Given inventory server with name limited to 3 characters in its metadata")
+	server := httptest.NewServer(corehttp.HandlerFunc(func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		if req.URL.Path == metadataRoute {
+			res.Write([]byte(`{"limits": {"max_name_length": 3}}`))
+			return
+		}
+		res.WriteHeader(201)
+		json.NewEncoder(res).Encode(Inventory{Id: 1})
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+	_, err := client.CreateItem(context.Background(), CreateInventory{Name: "Hammer"})
+	assert.NoError(t, err)
+
+	t.Logf("When refreshing the limits")
+	limits, err := client.RefreshLimits(context.Background())
+
+	t.Logf("Should validate items against the fetched limits until Update changes the service")
+	assert.NoError(t, err)
+	assert.Equal(t, Limits{MaxNameLength: 3}, limits)
+	assert.Equal(t, limits, client.Limits())
+	_, err = client.CreateItem(context.Background(), CreateInventory{Name: "Hammer"})
+	var tooLongError *TooLongError
+	assert.True(t, errors.As(err, &tooLongError))
+	assert.Equal(t, 3, tooLongError.Max)
+	_, err = client.UpdateItem(context.Background(), Inventory{Id: 1, Name: "Hammer"})
+	assert.True(t, errors.As(err, &tooLongError))
+
+	config := client.Config()
+	config.Timeout = 2 * time.Second
+	config.Limits = Limits{MaxNameLength: 10}
+	assert.NoError(t, client.Update(config))
+	assert.Equal(t, limits, client.Limits())
+
+	other := httptest.NewServer(server.Config.Handler)
+	defer other.Close()
+	otherUrl, _ := url.Parse(other.URL)
+	config.Url = *otherUrl
+	assert.NoError(t, client.Update(config))
+	assert.Equal(t, Limits{MaxNameLength: 10}, client.Limits())
+	_, err = client.CreateItem(context.Background(), CreateInventory{Name: "Hammer"})
+	assert.NoError(t, err)
+}
+
+func assertFieldErrors(t *testing.T, expected map[string]error, err error) {
+	if expected == nil {
+		assert.NoError(t, err)
+		return
+	}
+	var validationError *ValidationError
+	assert.True(t, errors.As(err, &validationError))
+	fields := map[string]error{}
+	for _, fieldError := range validationError.Errors {
+		fields[fieldError.Field] = fieldError.Err
+	}
+	assert.Equal(t, expected, fields)
+}
+
+func manyAttributes(count int) map[string]interface{} {
+	attributes := map[string]interface{}{}
+	for i := 0; i < count; i++ {
+		attributes[fmt.Sprint(i)] = i
+	}
+	return attributes
+}
@@ -0,0 +1,304 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	corehttp "net/http"
+	"sort"
+	"strings"
+	"test2/clock"
+	"test2/http"
+	"time"
+)
+
+const (
+	defaultWatchPollInterval = 5 * time.Second
+	defaultWatchMaxBackoff   = time.Minute
+)
+
+type ChangeType string
+
+const (
+	ItemAdded   ChangeType = "added"
+	ItemUpdated ChangeType = "updated"
+	ItemRemoved ChangeType = "removed"
+)
+
+// Change of a single item, Item holds its current state or the last known one when it was removed
+type Change struct {
+	Type ChangeType
+	Item Inventory
+}
+
+type WatchOptions struct {
+	// Optional id of the only item which changes are reported, the whole collection is watched when zero
+	ItemId int
+	// Delay between polls, defaults to 5s
+	Interval time.Duration
+	// How long the server may hold a poll until the collection changes, sent as Prefer: wait=<seconds>.
+	// Servers which don't confirm it with Preference-Applied are polled every Interval, zero disables long polling
+	LongPollWait time.Duration
+	// Delay after a failed poll doubles starting from Interval up to MaxBackoff, defaults to 1m
+	MaxBackoff time.Duration
+	// Reports every item of the first snapshot as added, otherwise the first snapshot is only the baseline
+	Initial bool
+	// Skips the event stream and always polls
+	PollOnly bool
+	// Optional, called with the error of every failed poll
+	OnError func(error)
+}
+
+// Watches the collection (or a single item) and sends its changes to the returned channel until ctx is done,
+// the channel is closed afterwards.
+//
+// Server-sent events of /inventory/events (item.created, item.updated and item.deleted with the item as data)
+// are preferred, the watcher falls back to polling when the server doesn't stream them or the stream ends for good.
+// Streams rejected with 401 or 403 are reported to WatchOptions.OnError before falling back.
+//
+// The collection is polled with conditional requests (If-None-Match), so unchanged collections are not transferred,
+// and snapshots are diffed into added, updated and removed changes. Polls are long when the server supports them.
+// Failed polls are reported to WatchOptions.OnError (and logged when Logging is enabled) and retried with backoff.
+//
+// If any of the durations of options is negative it returns WatchOptionsNegativeError.
+func (c *Client) Watch(ctx context.Context, options WatchOptions) (<-chan Change, error) {
+	if options.Interval < 0 || options.LongPollWait < 0 || options.MaxBackoff < 0 {
+		return nil, WatchOptionsNegativeError
+	}
+	if options.Interval == 0 {
+		options.Interval = defaultWatchPollInterval
+	}
+	if options.MaxBackoff == 0 {
+		options.MaxBackoff = defaultWatchMaxBackoff
+	}
+	changes := make(chan Change)
+	watcher := &watcher{client: c, options: options, changes: changes}
+	go watcher.run(ctx)
+	return changes, nil
+}
+
+type watcher struct {
+	client  *Client
+	options WatchOptions
+	changes chan Change
+
+	etag        string
+	longPolling bool
+	// Last known state of the watched items, nil until the first snapshot
+	snapshot map[int]Inventory
+}
+
+func (w *watcher) run(ctx context.Context) {
+	defer close(w.changes)
+
+	if !w.options.PollOnly && w.stream(ctx) {
+		return
+	}
+	var backoff time.Duration
+	for {
+		items, changed, err := w.poll(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := w.options.Interval
+		if err != nil {
+			w.reportError(err)
+			backoff = nextBackoff(backoff, w.options.Interval, w.options.MaxBackoff)
+			wait = backoff
+		} else {
+			backoff = 0
+			if changed && !w.update(ctx, w.filter(items)) {
+				return
+			}
+			if w.longPolling {
+				wait = 0
+			}
+		}
+
+		select {
+		case <-w.clock().After(wait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sends the changes between the last known and the current snapshot, returns false when ctx is done
+func (w *watcher) update(ctx context.Context, current map[int]Inventory) bool {
+	if w.snapshot != nil || w.options.Initial {
+		if !w.send(ctx, diff(w.snapshot, current)) {
+			return false
+		}
+	}
+	w.snapshot = current
+	return true
+}
+
+// Follows the event stream until it ends, returns true when ctx is done and false to fall back to polling
+func (w *watcher) stream(ctx context.Context) bool {
+	state := w.client.current()
+	path, err := state.route(eventsRoute, nil)
+	if err != nil {
+		return false
+	}
+	// The stream is dropped whenever the watcher falls back to polling, so it doesn't reconnect in the background
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := state.client.Stream(http.WithRoute(streamCtx, path.Route), path.String(), http.StreamOptions{OnError: w.reportError})
+	if err != nil {
+		if !isStreamUnsupported(err) {
+			w.reportError(err)
+		}
+		return ctx.Err() != nil
+	}
+
+	// The baseline is fetched once the stream is open, so no change falls in between
+	items, err := w.client.GetItems(ctx)
+	if err != nil {
+		w.reportError(err)
+		return ctx.Err() != nil
+	}
+	if !w.update(ctx, w.filter(items)) {
+		return true
+	}
+	for event := range events {
+		var item Inventory
+		if err := json.Unmarshal([]byte(event.Data), &item); err != nil {
+			w.reportError(fmt.Errorf("invalid %s event: %w", event.Event, err))
+			continue
+		}
+		if w.options.ItemId != 0 && item.Id != w.options.ItemId {
+			continue
+		}
+		current := map[int]Inventory{}
+		for id, known := range w.snapshot {
+			current[id] = known
+		}
+		switch event.Event {
+		case "item.created", "item.updated":
+			current[item.Id] = item
+		case "item.deleted":
+			delete(current, item.Id)
+		default:
+			continue
+		}
+		if !w.update(ctx, current) {
+			return true
+		}
+	}
+	return ctx.Err() != nil
+}
+
+// Servers which don't stream events respond with a client error or with something else than an event stream,
+// 401 and 403 are failed authentication rather than a missing stream, so they are still reported
+func isStreamUnsupported(err error) bool {
+	var httpError *http.ClientHttpError
+	var mediaTypeError *http.UnsupportedMediaTypeError
+	if errors.As(err, &httpError) {
+		return httpError.StatusCode < 500 &&
+			httpError.StatusCode != corehttp.StatusUnauthorized && httpError.StatusCode != corehttp.StatusForbidden
+	}
+	return errors.As(err, &mediaTypeError)
+}
+
+// Fetches the collection unless it's unchanged since the previous poll
+func (w *watcher) poll(ctx context.Context) ([]Inventory, bool, error) {
+	state := w.client.current()
+	path, err := state.route(itemsRoute, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	headers := http.Headers{}
+	if w.etag != "" {
+		headers["If-None-Match"] = w.etag
+	}
+	if w.options.LongPollWait > 0 {
+		headers["Prefer"] = fmt.Sprintf("wait=%d", int(math.Ceil(w.options.LongPollWait.Seconds())))
+		// Attempts of long polls have to outlive the wait
+		ctx = http.WithExtraTimeout(ctx, w.options.LongPollWait)
+	}
+	var items []Inventory
+	response, err := state.client.Do(http.WithHeaders(http.WithRoute(ctx, path.Route), headers), corehttp.MethodGet, path.String(), nil, &items)
+	if err != nil {
+		return nil, false, err
+	}
+	w.longPolling = w.options.LongPollWait > 0 && strings.Contains(response.Header.Get("Preference-Applied"), "wait")
+	if response.StatusCode == corehttp.StatusNotModified {
+		return nil, false, nil
+	}
+	w.etag = response.Header.Get("ETag")
+	return items, true, nil
+}
+
+func (w *watcher) filter(items []Inventory) map[int]Inventory {
+	snapshot := map[int]Inventory{}
+	for _, item := range items {
+		if w.options.ItemId == 0 || item.Id == w.options.ItemId {
+			snapshot[item.Id] = item
+		}
+	}
+	return snapshot
+}
+
+func (w *watcher) send(ctx context.Context, changes []Change) bool {
+	for _, change := range changes {
+		select {
+		case w.changes <- change:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+func (w *watcher) reportError(err error) {
+	if w.options.OnError != nil {
+		w.options.OnError(err)
+	}
+	if w.client.Config().Logging {
+		log.Printf("Watching inventory failed: %s \n", err)
+	}
+}
+
+func (w *watcher) clock() clock.Clock {
+	return clock.OrSystem(w.client.Config().Clock)
+}
+
+// Changes between the snapshots ordered by item ids
+func diff(previous map[int]Inventory, current map[int]Inventory) []Change {
+	var changes []Change
+	for id, item := range current {
+		before, ok := previous[id]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Type: ItemAdded, Item: item})
+		case !before.Equal(item):
+			changes = append(changes, Change{Type: ItemUpdated, Item: item})
+		}
+	}
+	for id, item := range previous {
+		if _, ok := current[id]; !ok {
+			changes = append(changes, Change{Type: ItemRemoved, Item: item})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Item.Id < changes[j].Item.Id
+	})
+	return changes
+}
+
+func nextBackoff(previous time.Duration, initial time.Duration, max time.Duration) time.Duration {
+	next := previous * 2
+	if previous == 0 {
+		next = initial
+	}
+	if next > max {
+		return max
+	}
+	return next
+}
@@ -0,0 +1,343 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	corehttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"test2/http"
+	"test2/http/retry"
+	"testing"
+	"time"
+)
+
+// Inventory server returning the collection with its version as ETag, long polls are held until a change
+// when longPoll is enabled
+type watchedServer struct {
+	mutex       sync.Mutex
+	changed     *sync.Cond
+	items       []Inventory
+	version     int
+	longPoll    bool
+	failures    int32
+	notModified int32
+}
+
+func newWatchedServer(items []Inventory, longPoll bool) *watchedServer {
+	server := &watchedServer{items: items, version: 1, longPoll: longPoll}
+	server.changed = sync.NewCond(&server.mutex)
+	return server
+}
+
+func (s *watchedServer) update(items []Inventory) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.items = items
+	s.version++
+	s.changed.Broadcast()
+}
+
+func (s *watchedServer) ServeHTTP(res corehttp.ResponseWriter, req *corehttp.Request) {
+	if req.URL.Path == "/inventory/events" {
+		res.WriteHeader(404)
+		return
+	}
+	if atomic.AddInt32(&s.failures, -1) >= 0 {
+		res.WriteHeader(400)
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	etag := func() string { return fmt.Sprintf(`"%d"`, s.version) }
+	if s.longPoll && req.Header.Get("Prefer") != "" {
+		res.Header().Set("Preference-Applied", req.Header.Get("Prefer"))
+		go func() {
+			<-req.Context().Done()
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			s.changed.Broadcast()
+		}()
+		for req.Header.Get("If-None-Match") == etag() && req.Context().Err() == nil {
+			s.changed.Wait()
+		}
+	}
+	if req.Header.Get("If-None-Match") == etag() {
+		atomic.AddInt32(&s.notModified, 1)
+		res.WriteHeader(304)
+		return
+	}
+	res.Header().Set("ETag", etag())
+	json.NewEncoder(res).Encode(s.items)
+}
+
+func TestClient_WatchWithPolling(t *testing.T) {
+	t.Logf("Given inventory server with 2 items supporting conditional requests")
+	watched := newWatchedServer([]Inventory{{Id: 1, Name: "Jan"}, {Id: 2, Name: "Adam"}}, false)
+	server := httptest.NewServer(watched)
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When watching the collection every 5ms")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := client.Watch(ctx, WatchOptions{Interval: 5 * time.Millisecond})
+	assert.NoError(t, err)
+
+	t.Logf("And when one item is updated, one removed and one added")
+	waitFor(t, func() bool { return atomic.LoadInt32(&watched.notModified) > 0 })
+	watched.update([]Inventory{{Id: 1, Name: "Janek"}, {Id: 3, Name: "Ewa"}})
+
+	t.Logf("Should send every change ordered by ids")
+	assert.Equal(t, Change{Type: ItemUpdated, Item: Inventory{Id: 1, Name: "Janek"}}, <-changes)
+	assert.Equal(t, Change{Type: ItemRemoved, Item: Inventory{Id: 2, Name: "Adam"}}, <-changes)
+	assert.Equal(t, Change{Type: ItemAdded, Item: Inventory{Id: 3, Name: "Ewa"}}, <-changes)
+
+	t.Logf("And should close the channel once ctx is done")
+	cancel()
+	for range changes {
+	}
+}
+
+func TestClient_WatchWithLongPolling(t *testing.T) {
+	t.Logf("Given inventory server holding polls until the collection changes")
+	watched := newWatchedServer([]Inventory{{Id: 1, Name: "Jan"}}, true)
+	server := httptest.NewServer(watched)
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When watching item 1 with long polls and 1h interval, reporting the initial snapshot")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := client.Watch(ctx, WatchOptions{ItemId: 1, Interval: time.Hour, LongPollWait: 30 * time.Second, Initial: true})
+	assert.NoError(t, err)
+	assert.Equal(t, Change{Type: ItemAdded, Item: Inventory{Id: 1, Name: "Jan"}}, <-changes)
+
+	t.Logf("And when the collection changes twice")
+	watched.update([]Inventory{{Id: 1, Name: "Jan"}, {Id: 2, Name: "Adam"}})
+	watched.update([]Inventory{{Id: 1, Name: "Janek"}, {Id: 2, Name: "Adam"}})
+
+	t.Logf("Should report only the change of item 1 without waiting for the interval")
+	assert.Equal(t, Change{Type: ItemUpdated, Item: Inventory{Id: 1, Name: "Janek"}}, <-changes)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&watched.notModified))
+}
+
+func TestClient_WatchWithLongPollsOutlivingTimeouts(t *testing.T) {
+	t.Logf("Given inventory server holding polls until the collection changes")
+	watched := newWatchedServer([]Inventory{{Id: 1, Name: "Jan"}}, true)
+	server := httptest.NewServer(watched)
+	defer server.Close()
+
+	t.Logf("And given Client with 50ms timeout and 60ms total timeout")
+	serverUrl, _ := url.Parse(server.URL)
+	client, err := NewClient(ClientConfig{
+		Timeout:       50 * time.Millisecond,
+		TotalTimeout:  60 * time.Millisecond,
+		Url:           *serverUrl,
+		RetriesConfig: retry.RetriesConfig{MaxRetries: 1, Delay: time.Millisecond, Factor: 2},
+	})
+	assert.NoError(t, err)
+
+	t.Logf("When watching the collection with long polls of 1s")
+	var errs int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := client.Watch(ctx, WatchOptions{Interval: time.Hour, LongPollWait: time.Second, OnError: func(error) {
+		atomic.AddInt32(&errs, 1)
+	}})
+	assert.NoError(t, err)
+
+	t.Logf("And when the collection changes after 200ms")
+	time.Sleep(200 * time.Millisecond)
+	watched.update([]Inventory{{Id: 1, Name: "Janek"}})
+
+	t.Logf("Should report the change without timing out the held poll")
+	assert.Equal(t, Change{Type: ItemUpdated, Item: Inventory{Id: 1, Name: "Janek"}}, <-changes)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&errs))
+}
+
+func TestClient_WatchWithFailingPolls(t *testing.T) {
+	t.Logf("Given inventory server failing the first 2 polls")
+	watched := newWatchedServer([]Inventory{{Id: 1, Name: "Jan"}}, false)
+	watched.failures = 2
+	server := httptest.NewServer(watched)
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When watching the collection with the initial snapshot")
+	var mutex sync.Mutex
+	var errs []error
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := client.Watch(ctx, WatchOptions{
+		Interval:   time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+		Initial:    true,
+		OnError: func(err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	assert.NoError(t, err)
+
+	t.Logf("Should report both errors and then the initial snapshot")
+	assert.Equal(t, Change{Type: ItemAdded, Item: Inventory{Id: 1, Name: "Jan"}}, <-changes)
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Len(t, errs, 2)
+}
+
+func TestClient_WatchWithEventStream(t *testing.T) {
+	t.Logf("Given inventory server streaming item events and returning 2 items")
+	var polls int32
+	server := httptest.NewServer(corehttp.HandlerFunc(func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		if req.URL.Path != "/inventory/events" {
+			atomic.AddInt32(&polls, 1)
+			json.NewEncoder(res).Encode([]Inventory{{Id: 1, Name: "Jan"}, {Id: 2, Name: "Adam"}})
+			return
+		}
+		res.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(res, "event: item.updated\ndata: {\"id\":1,\"name\":\"Janek\"}\n\n")
+		fmt.Fprint(res, "event: item.created\ndata: {\"id\":3,\"name\":\"Ewa\"}\n\n")
+		fmt.Fprint(res, "event: item.deleted\ndata: {\"id\":2}\n\n")
+		res.(corehttp.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When watching the collection")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := client.Watch(ctx, WatchOptions{Interval: time.Hour})
+	assert.NoError(t, err)
+
+	t.Logf("Should report the streamed changes against the fetched baseline")
+	assert.Equal(t, Change{Type: ItemUpdated, Item: Inventory{Id: 1, Name: "Janek"}}, <-changes)
+	assert.Equal(t, Change{Type: ItemAdded, Item: Inventory{Id: 3, Name: "Ewa"}}, <-changes)
+	assert.Equal(t, Change{Type: ItemRemoved, Item: Inventory{Id: 2, Name: "Adam"}}, <-changes)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&polls))
+}
+
+func TestClient_WatchWithFailingBaseline(t *testing.T) {
+	t.Logf("Given inventory server streaming events and failing the first fetch of the collection")
+	var open, fetches int32
+	server := httptest.NewServer(corehttp.HandlerFunc(func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		if req.URL.Path == "/inventory/events" {
+			atomic.AddInt32(&open, 1)
+			defer atomic.AddInt32(&open, -1)
+			res.Header().Set("Content-Type", "text/event-stream")
+			res.(corehttp.Flusher).Flush()
+			<-req.Context().Done()
+			return
+		}
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			res.WriteHeader(400)
+			return
+		}
+		json.NewEncoder(res).Encode([]Inventory{{Id: 1, Name: "Jan"}})
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When watching the collection with the initial snapshot")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := client.Watch(ctx, WatchOptions{Interval: time.Millisecond, Initial: true})
+	assert.NoError(t, err)
+
+	t.Logf("Should fall back to polling and close the event stream")
+	assert.Equal(t, Change{Type: ItemAdded, Item: Inventory{Id: 1, Name: "Jan"}}, <-changes)
+	waitFor(t, func() bool { return atomic.LoadInt32(&open) == 0 })
+}
+
+func TestClient_WatchWithUnauthorizedEventStream(t *testing.T) {
+	t.Logf("Given inventory server rejecting the event stream with 401 and returning 1 item")
+	server := httptest.NewServer(corehttp.HandlerFunc(func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		if req.URL.Path == "/inventory/events" {
+			res.WriteHeader(401)
+			return
+		}
+		json.NewEncoder(res).Encode([]Inventory{{Id: 1, Name: "Jan"}})
+	}))
+	defer server.Close()
+
+	t.Logf("And given Client")
+	client := newTestClient(t, server)
+
+	t.Logf("When watching the collection with the initial snapshot")
+	var mutex sync.Mutex
+	var errs []error
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := client.Watch(ctx, WatchOptions{
+		Interval: time.Hour,
+		Initial:  true,
+		OnError: func(err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	assert.NoError(t, err)
+
+	t.Logf("Should report the rejected stream and fall back to polling")
+	assert.Equal(t, Change{Type: ItemAdded, Item: Inventory{Id: 1, Name: "Jan"}}, <-changes)
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Len(t, errs, 1)
+	var httpError *http.ClientHttpError
+	assert.True(t, errors.As(errs[0], &httpError) && httpError.StatusCode == 401)
+}
+
+func TestClient_WatchWithNegativeInterval(t *testing.T) {
+	t.Logf("Given Client")
+	server := httptest.NewServer(watchedHandlerNotCalled(t))
+	defer server.Close()
+	client := newTestClient(t, server)
+
+	t.Logf("When watching with negative interval")
+	changes, err := client.Watch(context.Background(), WatchOptions{Interval: -time.Second})
+
+	t.Logf("Should return WatchOptionsNegativeError")
+	assert.Nil(t, changes)
+	assert.Equal(t, WatchOptionsNegativeError, err)
+}
+
+func TestDiff(t *testing.T) {
+	t.Logf("Given previous and current snapshots")
+	previous := map[int]Inventory{1: {Id: 1, Name: "Jan"}, 2: {Id: 2, Name: "Adam"}, 3: {Id: 3, Name: "Ewa"}}
+	current := map[int]Inventory{1: {Id: 1, Name: "Jan"}, 2: {Id: 2, Name: "Adam", Description: "new"}, 4: {Id: 4}}
+
+	t.Logf("When diffing them")
+	changes := diff(previous, current)
+
+	t.Logf("Should return changes ordered by ids, skipping unchanged items")
+	assert.Equal(t, []Change{
+		{Type: ItemUpdated, Item: Inventory{Id: 2, Name: "Adam", Description: "new"}},
+		{Type: ItemRemoved, Item: Inventory{Id: 3, Name: "Ewa"}},
+		{Type: ItemAdded, Item: Inventory{Id: 4}},
+	}, changes)
+}
+
+func watchedHandlerNotCalled(t *testing.T) corehttp.HandlerFunc {
+	return func(res corehttp.ResponseWriter, req *corehttp.Request) {
+		t.Errorf("unexpected request to [%s]", req.URL.String())
+	}
+}
@@ -0,0 +1,20 @@
+package webhook
+
+import "errors"
+
+// Errors returned during creation of the Handler by NewHandler
+var (
+	SecretEmptyError       = errors.New("secret cannot be empty")
+	CallbackMissingError   = errors.New("onEvent callback is required")
+	ToleranceNegativeError = errors.New("tolerance cannot be negative")
+)
+
+// Errors returned by Verify, the Handler rejects such requests with 401 status
+var (
+	MissingSignatureError = errors.New("signature or timestamp header is missing")
+	InvalidSignatureError = errors.New("signature doesn't match the body")
+	StaleTimestampError   = errors.New("timestamp is outside of the replay window")
+)
+
+// Returned by Unmarshal when the payload is not a valid event, the Handler rejects such requests with 400 status
+var InvalidEventError = errors.New("invalid event")
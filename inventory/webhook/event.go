@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"test2/inventory"
+	"time"
+)
+
+type EventType string
+
+const (
+	ItemCreatedEvent EventType = "item.created"
+	ItemUpdatedEvent EventType = "item.updated"
+	ItemDeletedEvent EventType = "item.deleted"
+)
+
+// Fields shared by all of the events
+type Metadata struct {
+	// Unique id of the event, redeliveries of the same event keep it
+	Id         string
+	OccurredAt time.Time
+}
+
+// One of ItemCreated, ItemUpdated or ItemDeleted
+type Event interface {
+	Type() EventType
+	Meta() Metadata
+	item() inventory.Inventory
+}
+
+type ItemCreated struct {
+	Metadata
+	Item inventory.Inventory
+}
+
+// Item holds the state after the update
+type ItemUpdated struct {
+	Metadata
+	Item inventory.Inventory
+}
+
+// Item holds the last state before the deletion
+type ItemDeleted struct {
+	Metadata
+	Item inventory.Inventory
+}
+
+func (e ItemCreated) Type() EventType           { return ItemCreatedEvent }
+func (e ItemCreated) Meta() Metadata            { return e.Metadata }
+func (e ItemCreated) item() inventory.Inventory { return e.Item }
+
+func (e ItemUpdated) Type() EventType           { return ItemUpdatedEvent }
+func (e ItemUpdated) Meta() Metadata            { return e.Metadata }
+func (e ItemUpdated) item() inventory.Inventory { return e.Item }
+
+func (e ItemDeleted) Type() EventType           { return ItemDeletedEvent }
+func (e ItemDeleted) Meta() Metadata            { return e.Metadata }
+func (e ItemDeleted) item() inventory.Inventory { return e.Item }
+
+// Wire format of every event
+type envelope struct {
+	Id         string              `json:"id"`
+	Type       EventType           `json:"type"`
+	OccurredAt time.Time           `json:"occurred_at"`
+	Item       inventory.Inventory `json:"item"`
+}
+
+// Encodes the event the same way the inventory service sends it
+func Marshal(event Event) ([]byte, error) {
+	meta := event.Meta()
+	return json.Marshal(envelope{Id: meta.Id, Type: event.Type(), OccurredAt: meta.OccurredAt, Item: event.item()})
+}
+
+// Decodes the payload into ItemCreated, ItemUpdated or ItemDeleted depending on its type.
+//
+// If the payload is not valid JSON, has no id or an unknown type it returns an error wrapping InvalidEventError.
+func Unmarshal(payload []byte) (Event, error) {
+	var decoded envelope
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, fmt.Errorf("%w: %s", InvalidEventError, err)
+	}
+	if decoded.Id == "" {
+		return nil, fmt.Errorf("%w: missing id", InvalidEventError)
+	}
+	meta := Metadata{Id: decoded.Id, OccurredAt: decoded.OccurredAt}
+	switch decoded.Type {
+	case ItemCreatedEvent:
+		return ItemCreated{Metadata: meta, Item: decoded.Item}, nil
+	case ItemUpdatedEvent:
+		return ItemUpdated{Metadata: meta, Item: decoded.Item}, nil
+	case ItemDeletedEvent:
+		return ItemDeleted{Metadata: meta, Item: decoded.Item}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type [%s]", InvalidEventError, decoded.Type)
+	}
+}
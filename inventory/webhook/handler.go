@@ -0,0 +1,177 @@
+// Provides a net/http handler receiving inventory change events sent by the inventory service as webhooks.
+//
+// The NewHandler function creates the Handler from HandlerConfig, it verifies HMAC signatures of the deliveries,
+// rejects the ones outside of the replay window, skips redeliveries of the same event and hands ItemCreated,
+// ItemUpdated and ItemDeleted events to HandlerConfig.OnEvent.
+//
+// The webhooktest package signs and sends fake events in tests.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"test2/clock"
+	"time"
+)
+
+const (
+	defaultTolerance   = 5 * time.Minute
+	defaultMaxBodySize = 1 << 20
+)
+
+type HandlerConfig struct {
+	// Secret shared with the inventory service
+	Secret []byte
+	// Maximum difference between the timestamp of a delivery and now, defaults to 5m.
+	// Ids of handled events are remembered for twice as long, so any redelivery within the window is skipped
+	Tolerance time.Duration
+	// Called once for every event, an error responds with 500 status so the event is redelivered later
+	OnEvent func(ctx context.Context, event Event) error
+	// Bodies larger than that are rejected, defaults to 1MB
+	MaxBodySize int64
+	// Optional clock used for the replay window, defaults to clock.System
+	Clock   clock.Clock
+	Logging bool
+}
+
+// Constructed with NewHandler, safe for concurrent use
+type Handler struct {
+	config HandlerConfig
+	mutex  sync.Mutex
+	// Ids of events being handled (zero time) or handled until the time they're forgotten
+	seen      map[string]time.Time
+	nextSweep time.Time
+}
+
+// Creates the Handler
+//
+// If HandlerConfig.Secret is empty it returns SecretEmptyError, if HandlerConfig.OnEvent is nil
+// CallbackMissingError and if HandlerConfig.Tolerance is below zero ToleranceNegativeError.
+func NewHandler(config HandlerConfig) (*Handler, error) {
+	if len(config.Secret) == 0 {
+		return nil, SecretEmptyError
+	}
+	if config.OnEvent == nil {
+		return nil, CallbackMissingError
+	}
+	if config.Tolerance < 0 {
+		return nil, ToleranceNegativeError
+	}
+	if config.Tolerance == 0 {
+		config.Tolerance = defaultTolerance
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultMaxBodySize
+	}
+	config.Clock = clock.OrSystem(config.Clock)
+	return &Handler{config: config, seen: map[string]time.Time{}}, nil
+}
+
+// Responds with 204 status once the event is handled or when it was already handled,
+// 401 when the signature is invalid or stale, 400 when the event can't be decoded, 409 when the same event
+// is being handled by a concurrent delivery and 500 when OnEvent fails
+func (h *Handler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.Header().Set("Allow", http.MethodPost)
+		h.reject(res, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, h.config.MaxBodySize+1))
+	if err != nil {
+		h.reject(res, http.StatusBadRequest, err)
+		return
+	}
+	if int64(len(body)) > h.config.MaxBodySize {
+		h.reject(res, http.StatusRequestEntityTooLarge, errors.New("body too large"))
+		return
+	}
+	if err := Verify(req.Header, body, h.config.Secret, h.config.Clock.Now(), h.config.Tolerance); err != nil {
+		h.reject(res, http.StatusUnauthorized, err)
+		return
+	}
+	event, err := Unmarshal(body)
+	if err != nil {
+		h.reject(res, http.StatusBadRequest, err)
+		return
+	}
+
+	id := event.Meta().Id
+	switch h.claim(id) {
+	case claimHandled:
+		res.WriteHeader(http.StatusNoContent)
+		return
+	case claimInFlight:
+		h.reject(res, http.StatusConflict, errors.New("event ["+id+"] is being handled"))
+		return
+	}
+	if err := h.config.OnEvent(req.Context(), event); err != nil {
+		h.release(id)
+		h.reject(res, http.StatusInternalServerError, err)
+		return
+	}
+	h.complete(id)
+	res.WriteHeader(http.StatusNoContent)
+}
+
+type claim int
+
+const (
+	claimed claim = iota
+	claimHandled
+	claimInFlight
+)
+
+// Marks the event as being handled unless it's already handled or in flight
+func (h *Handler) claim(id string) claim {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	now := h.config.Clock.Now()
+	h.sweep(now)
+
+	forgetAt, ok := h.seen[id]
+	switch {
+	case ok && forgetAt.IsZero():
+		return claimInFlight
+	case ok && now.Before(forgetAt):
+		return claimHandled
+	}
+	h.seen[id] = time.Time{}
+	return claimed
+}
+
+func (h *Handler) complete(id string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.seen[id] = h.config.Clock.Now().Add(2 * h.config.Tolerance)
+}
+
+// Forgets the event which failed, so its redelivery is handled again
+func (h *Handler) release(id string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.seen, id)
+}
+
+// Forgets handled events once their redeliveries would be stale anyway, at most once per Tolerance
+func (h *Handler) sweep(now time.Time) {
+	if now.Before(h.nextSweep) {
+		return
+	}
+	for id, forgetAt := range h.seen {
+		if !forgetAt.IsZero() && !now.Before(forgetAt) {
+			delete(h.seen, id)
+		}
+	}
+	h.nextSweep = now.Add(h.config.Tolerance)
+}
+
+func (h *Handler) reject(res http.ResponseWriter, statusCode int, err error) {
+	if h.config.Logging {
+		log.Printf("Inventory webhook rejected with [%d]: %s \n", statusCode, err)
+	}
+	http.Error(res, http.StatusText(statusCode), statusCode)
+}
@@ -0,0 +1,194 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"test2/clock"
+	"test2/inventory"
+	"test2/inventory/webhook"
+	"test2/inventory/webhook/webhooktest"
+	"testing"
+	"time"
+)
+
+var (
+	secret = []byte("top-secret")
+	now    = time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	item   = inventory.Inventory{Id: 1, Name: "Jan", Description: "Kowalski"}
+)
+
+func TestNewHandlerWithInvalidConfig(t *testing.T) {
+	onEvent := func(context.Context, webhook.Event) error { return nil }
+	testCases := []struct {
+		Config        webhook.HandlerConfig
+		ExpectedError error
+	}{
+		{Config: webhook.HandlerConfig{OnEvent: onEvent}, ExpectedError: webhook.SecretEmptyError},
+		{Config: webhook.HandlerConfig{Secret: secret}, ExpectedError: webhook.CallbackMissingError},
+		{Config: webhook.HandlerConfig{Secret: secret, OnEvent: onEvent, Tolerance: -time.Second}, ExpectedError: webhook.ToleranceNegativeError},
+	}
+	for _, testCase := range testCases {
+		t.Logf("Given invalid HandlerConfig")
+
+		t.Logf("When creating Handler")
+		handler, err := webhook.NewHandler(testCase.Config)
+
+		t.Logf("Should return '%s' error", testCase.ExpectedError)
+		assert.Nil(t, handler)
+		assert.Equal(t, testCase.ExpectedError, err)
+	}
+}
+
+func TestHandlerWithEveryEventType(t *testing.T) {
+	t.Logf("Given Handler recording events")
+	handler, events := newRecordingHandler(t)
+
+	t.Logf("When serving created, updated and deleted events")
+	sent := []webhook.Event{
+		webhook.ItemCreated{Metadata: webhook.Metadata{Id: "evt-1", OccurredAt: now}, Item: item},
+		webhook.ItemUpdated{Metadata: webhook.Metadata{Id: "evt-2", OccurredAt: now}, Item: item},
+		webhook.ItemDeleted{Metadata: webhook.Metadata{Id: "evt-3", OccurredAt: now}, Item: item},
+	}
+	for _, event := range sent {
+		response := webhooktest.Serve(handler, secret, event, now)
+		assert.Equal(t, http.StatusNoContent, response.Code)
+	}
+
+	t.Logf("Should pass typed events to the callback")
+	assert.Equal(t, sent, *events)
+}
+
+func TestHandlerWithRedeliveredEvent(t *testing.T) {
+	t.Logf("Given Handler recording events")
+	handler, events := newRecordingHandler(t)
+	event := webhook.ItemCreated{Metadata: webhook.Metadata{Id: "evt-1", OccurredAt: now}, Item: item}
+
+	t.Logf("When serving the same event twice")
+	first := webhooktest.Serve(handler, secret, event, now)
+	second := webhooktest.Serve(handler, secret, event, now.Add(time.Second))
+
+	t.Logf("Should acknowledge both and call the callback once")
+	assert.Equal(t, http.StatusNoContent, first.Code)
+	assert.Equal(t, http.StatusNoContent, second.Code)
+	assert.Len(t, *events, 1)
+}
+
+func TestHandlerWithFailingCallback(t *testing.T) {
+	t.Logf("Given Handler which callback fails once")
+	var calls int
+	handler, err := webhook.NewHandler(webhook.HandlerConfig{
+		Secret: secret,
+		Clock:  clock.NewManual(now),
+		OnEvent: func(context.Context, webhook.Event) error {
+			calls++
+			if calls == 1 {
+				return errors.New("cache unavailable")
+			}
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+	event := webhook.ItemUpdated{Metadata: webhook.Metadata{Id: "evt-1", OccurredAt: now}, Item: item}
+
+	t.Logf("When serving the event and then its redelivery")
+	first := webhooktest.Serve(handler, secret, event, now)
+	second := webhooktest.Serve(handler, secret, event, now)
+
+	t.Logf("Should respond with 500 status and handle the redelivery")
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Equal(t, http.StatusNoContent, second.Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestHandlerWithInvalidDeliveries(t *testing.T) {
+	event := webhook.ItemDeleted{Metadata: webhook.Metadata{Id: "evt-1", OccurredAt: now}, Item: item}
+	testCases := []struct {
+		Name         string
+		Request      func() *http.Request
+		ExpectedCode int
+	}{
+		{Name: "wrong secret", ExpectedCode: http.StatusUnauthorized, Request: func() *http.Request {
+			request, _ := webhooktest.NewRequest(context.Background(), "http://localhost", []byte("other"), event, now)
+			return request
+		}},
+		{Name: "timestamp older than tolerance", ExpectedCode: http.StatusUnauthorized, Request: func() *http.Request {
+			request, _ := webhooktest.NewRequest(context.Background(), "http://localhost", secret, event, now.Add(-6*time.Minute))
+			return request
+		}},
+		{Name: "missing signature", ExpectedCode: http.StatusUnauthorized, Request: func() *http.Request {
+			request, _ := webhooktest.NewRequest(context.Background(), "http://localhost", secret, event, now)
+			request.Header.Del(webhook.SignatureHeader)
+			return request
+		}},
+		{Name: "unknown event type", ExpectedCode: http.StatusBadRequest, Request: func() *http.Request {
+			body := `{"id":"evt-1","type":"item.archived"}`
+			request := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+			request.Header.Set(webhook.TimestampHeader, "1614600000")
+			request.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, now, []byte(body)))
+			return request
+		}},
+		{Name: "GET method", ExpectedCode: http.StatusMethodNotAllowed, Request: func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		}},
+	}
+	for _, testCase := range testCases {
+		t.Logf("Given Handler recording events")
+		handler, events := newRecordingHandler(t)
+
+		t.Logf("When serving a delivery with %s", testCase.Name)
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, testCase.Request())
+
+		t.Logf("Should respond with %d status and not call the callback", testCase.ExpectedCode)
+		assert.Equal(t, testCase.ExpectedCode, response.Code)
+		assert.Empty(t, *events)
+	}
+}
+
+func TestHandlerOverHttp(t *testing.T) {
+	t.Logf("Given HTTP server with Handler on system clock")
+	var mutex sync.Mutex
+	var received []webhook.Event
+	handler, err := webhook.NewHandler(webhook.HandlerConfig{
+		Secret: secret,
+		OnEvent: func(ctx context.Context, event webhook.Event) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			received = append(received, event)
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Logf("When sending an event")
+	event := webhook.ItemCreated{Metadata: webhook.Metadata{Id: "evt-1", OccurredAt: now}, Item: item}
+	response, err := webhooktest.Send(context.Background(), server.URL, secret, event)
+
+	t.Logf("Should respond with 204 status and pass the event to the callback")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, []webhook.Event{event}, received)
+}
+
+func newRecordingHandler(t *testing.T) (*webhook.Handler, *[]webhook.Event) {
+	var events []webhook.Event
+	handler, err := webhook.NewHandler(webhook.HandlerConfig{
+		Secret: secret,
+		Clock:  clock.NewManual(now),
+		OnEvent: func(ctx context.Context, event webhook.Event) error {
+			events = append(events, event)
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+	return handler, &events
+}
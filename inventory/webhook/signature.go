@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers set by the inventory service on every delivery
+const (
+	// Unix time in seconds at which the delivery was signed
+	TimestampHeader = "X-Inventory-Timestamp"
+	// sha256=<hex of HMAC-SHA256 of "<timestamp>.<body>">
+	SignatureHeader = "X-Inventory-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+// Signs the body sent at timestamp, the result is the value of SignatureHeader
+func Sign(secret []byte, timestamp time.Time, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(mac(secret, strconv.FormatInt(timestamp.Unix(), 10), body))
+}
+
+// Verifies the signature of the body against the headers of the delivery.
+//
+// If any of the headers is missing it returns MissingSignatureError, if the signature doesn't match
+// InvalidSignatureError and if the timestamp differs from now by more than tolerance StaleTimestampError.
+func Verify(header http.Header, body []byte, secret []byte, now time.Time, tolerance time.Duration) error {
+	timestamp := header.Get(TimestampHeader)
+	signature := header.Get(SignatureHeader)
+	if timestamp == "" || signature == "" {
+		return MissingSignatureError
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return InvalidSignatureError
+	}
+	decoded, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil || !strings.HasPrefix(signature, signaturePrefix) || !hmac.Equal(decoded, mac(secret, timestamp, body)) {
+		return InvalidSignatureError
+	}
+	// Checked after the signature, so the timestamp can't be forged to pass the window
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return StaleTimestampError
+	}
+	return nil
+}
+
+func mac(secret []byte, timestamp string, body []byte) []byte {
+	hash := hmac.New(sha256.New, secret)
+	hash.Write([]byte(timestamp))
+	hash.Write([]byte("."))
+	hash.Write(body)
+	return hash.Sum(nil)
+}
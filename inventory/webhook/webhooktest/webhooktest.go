@@ -0,0 +1,48 @@
+// Signs and sends fake inventory events to webhook handlers in tests.
+package webhooktest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"test2/inventory/webhook"
+	"time"
+)
+
+// Builds a POST request to url with the event signed with secret at timestamp
+func NewRequest(ctx context.Context, url string, secret []byte, event webhook.Event, timestamp time.Time) (*http.Request, error) {
+	body, err := webhook.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhook.TimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+	request.Header.Set(webhook.SignatureHeader, webhook.Sign(secret, timestamp, body))
+	return request, nil
+}
+
+// Serves the event signed with secret at timestamp by handler directly, without any network
+func Serve(handler http.Handler, secret []byte, event webhook.Event, timestamp time.Time) *httptest.ResponseRecorder {
+	request, err := NewRequest(context.Background(), "http://localhost/webhook", secret, event, timestamp)
+	if err != nil {
+		panic(err)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// Sends the event signed with secret now to url, e.g. of a httptest.Server
+func Send(ctx context.Context, url string, secret []byte, event webhook.Event) (*http.Response, error) {
+	request, err := NewRequest(ctx, url, secret, event, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(request)
+}
@@ -2,26 +2,44 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
-	"net/url"
-	"test2/http/retry"
 	"test2/inventory"
 	"time"
 )
 
 func main() {
-	inventoryClient, err := inventory.NewClient(inventory.ClientConfig{
-		Timeout: time.Second,
+	configFile := flag.String("config", "", "Optional YAML, JSON or TOML config file")
+	inventory.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	settings := inventory.Settings{
+		Url:     "https://inventory.raspicluster.pl",
+		Timeout: inventory.Duration(time.Second),
 		Logging: true,
-		Url: url.URL{
-			Scheme: "https",
-			Host:   "inventory.raspicluster.pl"},
-		RetriesConfig: retry.RetriesConfig{
+		Retries: inventory.RetriesSettings{
 			MaxRetries: 3,
-			Delay:      time.Second,
+			Delay:      inventory.Duration(time.Second),
 			Factor:     1,
 		},
-	})
+	}
+	if *configFile != "" {
+		if err := inventory.LoadFile(*configFile, &settings); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := inventory.LoadEnv(&settings); err != nil {
+		log.Fatal(err)
+	}
+	if err := inventory.LoadFlags(flag.CommandLine, &settings); err != nil {
+		log.Fatal(err)
+	}
+	config, err := settings.ClientConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	inventoryClient, err := inventory.NewClient(config)
 	if err != nil {
 		log.Fatal(err)
 	}